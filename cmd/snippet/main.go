@@ -0,0 +1,13 @@
+// Command snippet is the command-line client for snippetbox. See package
+// cli for its commands.
+package main
+
+import (
+	"os"
+
+	"github.com/xyedo/snippetbox/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
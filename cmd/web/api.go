@@ -0,0 +1,566 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/validator"
+)
+
+// The /api/v1 endpoints serve scripts and internal tools rather than
+// browsers. They speak JSON, authenticate with API tokens instead of
+// sessions and therefore need no CSRF tokens. Every error response has the
+// form of apiErrorEnvelope.
+
+// maxAPIBodySize is the largest JSON request body the API reads. Snippet
+// content is limited to maxContentSize bytes, but JSON escaping can make it
+// several times longer.
+const maxAPIBodySize = 1 << 20
+
+// apiSnippet is how the API represents a snippet. Content and Tags are only
+// included for single snippets, and Expires is null for snippets that never
+// expire.
+type apiSnippet struct {
+	Slug              string            `json:"slug"`
+	URL               string            `json:"url"`
+	Author            string            `json:"author,omitempty"`
+	Title             string            `json:"title"`
+	Content           *string           `json:"content,omitempty"`
+	Language          string            `json:"language"`
+	Visibility        models.Visibility `json:"visibility"`
+	BurnAfterReading  bool              `json:"burn_after_reading"`
+	PasswordProtected bool              `json:"password_protected"`
+	Encrypted         bool              `json:"encrypted"`
+	Tags              []string          `json:"tags,omitempty"`
+	Created           time.Time         `json:"created"`
+	Expires           *time.Time        `json:"expires"`
+}
+
+func (app *application) newAPISnippet(s *models.Snippet) apiSnippet {
+	a := apiSnippet{
+		Slug:              s.Slug,
+		URL:               fmt.Sprintf("%s/s/%s", app.baseURL, s.Slug),
+		Author:            s.Author,
+		Title:             s.Title,
+		Language:          s.Language,
+		Visibility:        s.Visibility,
+		BurnAfterReading:  s.BurnAfterReading,
+		PasswordProtected: s.PasswordProtected(),
+		Encrypted:         s.Encrypted,
+		Created:           s.Created,
+	}
+	if !s.NeverExpires() {
+		a.Expires = &s.Expires
+	}
+	return a
+}
+
+// newAPISnippetDetail is like newAPISnippet but includes the content and
+// tags.
+func (app *application) newAPISnippetDetail(s *models.Snippet) apiSnippet {
+	a := app.newAPISnippet(s)
+	a.Content = &s.Content
+	a.Tags = s.Tags
+	if a.Tags == nil {
+		a.Tags = []string{}
+	}
+	return a
+}
+
+func (app *application) newAPISnippets(snippets []*models.Snippet) []apiSnippet {
+	list := make([]apiSnippet, len(snippets))
+	for i, s := range snippets {
+		list[i] = app.newAPISnippet(s)
+	}
+	return list
+}
+
+type apiUser struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Email   string    `json:"email"`
+	Created time.Time `json:"created"`
+	// Verified is false until the user followed the link emailed to them,
+	// and only verified users can create snippets.
+	Verified bool `json:"verified"`
+}
+
+// The bodies of successful responses wrap what they return in an object, so
+// that fields can be added next to it later.
+
+type apiTokenResponse struct {
+	Token string `json:"token"`
+}
+
+type apiUserResponse struct {
+	User apiUser `json:"user"`
+}
+
+type apiSnippetResponse struct {
+	Snippet apiSnippet `json:"snippet"`
+}
+
+// apiSnippetsResponse is a list of snippets. Prev and Next are only set for
+// pages of the archive.
+type apiSnippetsResponse struct {
+	Snippets []apiSnippet `json:"snippets"`
+	Prev     string       `json:"prev,omitempty"`
+	Next     string       `json:"next,omitempty"`
+}
+
+// apiErrorEnvelope is the body of every API error response. Fields holds
+// the errors of individual fields when a request fails validation, keyed by
+// the name of the field.
+type apiErrorEnvelope struct {
+	Error struct {
+		Status  int               `json:"status"`
+		Message string            `json:"message"`
+		Fields  map[string]string `json:"fields,omitempty"`
+	} `json:"error"`
+}
+
+func (app *application) writeJSON(w http.ResponseWriter, status int, v any) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(js, '\n'))
+}
+
+func (app *application) apiError(w http.ResponseWriter, status int, message string) {
+	var env apiErrorEnvelope
+	env.Error.Status = status
+	env.Error.Message = message
+	app.writeJSON(w, status, env)
+}
+
+func (app *application) apiServerError(w http.ResponseWriter, err error) {
+	app.errorLog.Output(2, fmt.Sprintf("%s\n%s", err.Error(), debug.Stack()))
+	app.apiError(w, http.StatusInternalServerError, "the server encountered a problem and could not process the request")
+}
+
+func (app *application) apiNotFound(w http.ResponseWriter) {
+	app.apiError(w, http.StatusNotFound, "the requested resource could not be found")
+}
+
+// apiValidationError reports the errors of a form that failed validation.
+func (app *application) apiValidationError(w http.ResponseWriter, v validator.Validator) {
+	var env apiErrorEnvelope
+	env.Error.Status = http.StatusUnprocessableEntity
+	env.Error.Message = "the request failed validation"
+	if len(v.NonFieldErrors) > 0 {
+		env.Error.Message = strings.Join(v.NonFieldErrors, "; ")
+	}
+	env.Error.Fields = v.FieldErrors
+	app.writeJSON(w, http.StatusUnprocessableEntity, env)
+}
+
+// errBodyTooLarge is returned by readJSON for bodies over maxAPIBodySize.
+var errBodyTooLarge = fmt.Errorf("body must not be larger than %d bytes", maxAPIBodySize)
+
+// readJSON decodes a request body holding a single JSON object into dst. Its
+// errors are meant for the client.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodySize))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains badly-formed JSON")
+		case errors.As(err, &typeError):
+			if typeError.Field != "" {
+				return fmt.Errorf("body contains incorrect JSON type for field %q", typeError.Field)
+			}
+			return fmt.Errorf("body contains incorrect JSON type (at character %d)", typeError.Offset)
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return fmt.Errorf("body contains unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		case err.Error() == "http: request body too large":
+			return errBodyTooLarge
+		}
+		return err
+	}
+	if err = dec.Decode(&struct{}{}); err != io.EOF {
+		return errors.New("body must only contain a single JSON object")
+	}
+	return nil
+}
+
+// readJSONRequest is readJSON for handlers: it also requires a JSON content
+// type, and writes the error response itself when it returns false.
+func (app *application) readJSONRequest(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/json") {
+		app.apiError(w, http.StatusUnsupportedMediaType, "body must be application/json")
+		return false
+	}
+	if err := app.readJSON(w, r, dst); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errBodyTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		app.apiError(w, status, err.Error())
+		return false
+	}
+	return true
+}
+
+// apiLookupSnippet loads the snippet named by the :slug route parameter as seen
+// by userID. When it can't be loaded, the error response has already been
+// written and ok is false.
+func (app *application) apiLookupSnippet(w http.ResponseWriter, r *http.Request, userID int) (snippet *models.Snippet, ok bool) {
+	slug := httprouter.ParamsFromContext(r.Context()).ByName("slug")
+	snippet, err := app.snippets.GetBySlug(slug, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+			return nil, false
+		}
+		app.apiServerError(w, err)
+		return nil, false
+	}
+	return snippet, true
+}
+
+// apiOwnedSnippet is like apiLookupSnippet but also makes sure the snippet
+// belongs to userID.
+func (app *application) apiOwnedSnippet(w http.ResponseWriter, r *http.Request, userID int) (snippet *models.Snippet, ok bool) {
+	snippet, ok = app.apiLookupSnippet(w, r, userID)
+	if !ok {
+		return nil, false
+	}
+	if !snippet.OwnedBy(userID) {
+		app.apiError(w, http.StatusForbidden, "only the owner of a snippet can change it")
+		return nil, false
+	}
+	return snippet, true
+}
+
+type apiLoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// Name is what the token is listed as on the account page.
+	Name string `json:"name,omitempty"`
+	// Scopes default to read only, and ExpiresDays to apiLoginLifetime; 0
+	// asks for a token that never expires. Clients have to ask for more
+	// power explicitly.
+	Scopes      []models.Scope `json:"scopes,omitempty"`
+	ExpiresDays *int           `json:"expires_days,omitempty"`
+}
+
+// apiLoginLifetime is how many days tokens from API logins are valid for
+// unless the request says otherwise.
+const apiLoginLifetime = 30
+
+// apiLogin exchanges an email and password for a new API token.
+func (app *application) apiLogin(w http.ResponseWriter, r *http.Request) {
+	var req apiLoginRequest
+	if !app.readJSONRequest(w, r, &req) {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = "API login"
+	}
+	if req.Scopes == nil {
+		req.Scopes = []models.Scope{models.ScopeRead}
+	}
+	expiresDays := apiLoginLifetime
+	if req.ExpiresDays != nil {
+		expiresDays = *req.ExpiresDays
+	}
+	var v validator.Validator
+	v.CheckField(validator.NotBlank(req.Email), "email", "This field cannot be blank")
+	v.CheckField(validator.NotBlank(req.Password), "password", "This field cannot be blank")
+	validateToken(&v, req.Name, req.Scopes, expiresDays)
+	if !v.Valid() {
+		app.apiValidationError(w, v)
+		return
+	}
+	// Failed attempts are counted per client and address, so that the
+	// endpoint can't be used to guess passwords.
+	limiterKey := clientIP(r) + " " + strings.ToLower(strings.TrimSpace(req.Email))
+	if app.loginLimiter.Exceeded(limiterKey) {
+		app.apiError(w, http.StatusTooManyRequests, "too many failed attempts, try again later")
+		return
+	}
+	userID, err := app.users.Authenticate(req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			app.loginLimiter.Allow(limiterKey)
+			app.apiError(w, http.StatusUnauthorized, "email or password is incorrect")
+			return
+		}
+		app.apiServerError(w, err)
+		return
+	}
+	_, token, err := app.tokens.New(userID, req.Name, req.Scopes, tokenExpiry(time.Now().UTC(), expiresDays))
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusCreated, apiTokenResponse{Token: token})
+}
+
+// apiCurrentUser describes the token's user.
+func (app *application) apiCurrentUser(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	user, err := app.users.Get(userID)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, apiUserResponse{User: apiUser{
+		ID:       user.ID,
+		Name:     user.Name,
+		Email:    user.Email,
+		Created:  user.Created,
+		Verified: user.Verified,
+	}})
+}
+
+// apiUserSnippets lists the snippets of the token's user, newest first.
+func (app *application) apiUserSnippets(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	snippets, err := app.snippets.ByUser(userID)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, apiSnippetsResponse{Snippets: app.newAPISnippets(snippets)})
+}
+
+// apiSnippetList pages through the archive of listed snippets like
+// /snippets does, taking the same query parameters. Prev and Next are the
+// cursors to pass as before and after to get the neighbouring pages.
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	page, cursor, err := app.parseArchiveQuery(r.URL.Query())
+	if err != nil {
+		app.apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	snippets, err := app.archive(page, cursor)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, apiSnippetsResponse{
+		Snippets: app.newAPISnippets(snippets),
+		Prev:     page.Prev,
+		Next:     page.Next,
+	})
+}
+
+// apiSnippetGet returns a snippet with its content. Like the raw content
+// endpoint it doesn't burn snippets or unlock them: burn-after-reading
+// snippets are only found by their owner, and password-protected ones are
+// only readable by their owner.
+func (app *application) apiSnippetGet(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	snippet, ok := app.apiLookupSnippet(w, r, userID)
+	if !ok {
+		return
+	}
+	if !snippet.OwnedBy(userID) {
+		if snippet.BurnAfterReading {
+			app.apiNotFound(w)
+			return
+		}
+		if snippet.PasswordProtected() {
+			app.apiError(w, http.StatusForbidden, "the snippet is locked with a password, open it in a browser")
+			return
+		}
+	}
+	app.writeJSON(w, http.StatusOK, apiSnippetResponse{Snippet: app.newAPISnippetDetail(snippet)})
+}
+
+// apiSnippetInput is the body of requests creating or updating a snippet.
+// Fields left out keep their defaults, or their current values when
+// updating. Expires is a number followed by m, h or d, or "never".
+type apiSnippetInput struct {
+	Title            *string            `json:"title,omitempty"`
+	Content          *string            `json:"content,omitempty"`
+	Language         *string            `json:"language,omitempty"`
+	Expires          *string            `json:"expires,omitempty"`
+	Visibility       *models.Visibility `json:"visibility,omitempty"`
+	BurnAfterReading *bool              `json:"burn_after_reading,omitempty"`
+	Password         *string            `json:"password,omitempty"`
+	Tags             *[]string          `json:"tags,omitempty"`
+}
+
+// apply copies the fields of the input that were set to a snippet form.
+func (in *apiSnippetInput) apply(form *snippetCreateForm) {
+	if in.Title != nil {
+		form.Title = *in.Title
+	}
+	if in.Content != nil {
+		form.Content = *in.Content
+	}
+	if in.Language != nil {
+		form.Language = *in.Language
+	}
+	if in.Expires != nil {
+		form.parseExpiry(*in.Expires)
+	}
+	if in.Visibility != nil {
+		form.Visibility = *in.Visibility
+	}
+	if in.BurnAfterReading != nil {
+		form.BurnAfterReading = *in.BurnAfterReading
+	}
+	if in.Password != nil {
+		form.Password = *in.Password
+	}
+	if in.Tags != nil {
+		form.Tags = strings.Join(*in.Tags, " ")
+	}
+}
+
+// apiSnippetCreate creates a snippet owned by the token's user. Snippets are
+// public and expire after a week unless the request says otherwise.
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	var in apiSnippetInput
+	if !app.readJSONRequest(w, r, &in) {
+		return
+	}
+	form := snippetCreateForm{
+		Expires:     7,
+		ExpiresUnit: "days",
+		Visibility:  models.VisibilityPublic,
+	}
+	in.apply(&form)
+	now := time.Now().UTC()
+	form.validate(now, app.maxExpiry)
+	if !form.Valid() {
+		app.apiValidationError(w, form.Validator)
+		return
+	}
+	snippet := &models.Snippet{
+		UserID:           userID,
+		Title:            form.Title,
+		Content:          form.Content,
+		Visibility:       form.Visibility,
+		BurnAfterReading: form.BurnAfterReading,
+		Tags:             parseTags(form.Tags),
+		Expires:          form.expiresAt(now),
+	}
+	snippet.Language, snippet.LanguageConfidence = form.language()
+	if err := app.snippets.Insert(snippet, form.Password); err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	a := app.newAPISnippetDetail(snippet)
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/snippets/%s", snippet.Slug))
+	app.writeJSON(w, http.StatusCreated, apiSnippetResponse{Snippet: a})
+}
+
+// apiSnippetUpdate changes the fields of a snippet given in the request,
+// like the edit form does. Whether a snippet burns after reading and its
+// password can only be chosen when it is created, and encrypted snippets
+// can't be edited at all.
+func (app *application) apiSnippetUpdate(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	snippet, ok := app.apiOwnedSnippet(w, r, userID)
+	if !ok {
+		return
+	}
+	if snippet.Encrypted {
+		app.apiError(w, http.StatusConflict, "encrypted snippets can't be edited")
+		return
+	}
+	var in apiSnippetInput
+	if !app.readJSONRequest(w, r, &in) {
+		return
+	}
+	form := snippetCreateForm{
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Language:   languageChoice(snippet),
+		Visibility: snippet.Visibility,
+		Tags:       strings.Join(snippet.Tags, " "),
+		// The current expiry is kept below unless a new one is given.
+		ExpiresUnit: "never",
+	}
+	in.apply(&form)
+	now := time.Now().UTC()
+	form.validate(now, app.maxExpiry)
+	if in.BurnAfterReading != nil {
+		form.AddFieldError("burn_after_reading", "This field can only be set when the snippet is created")
+	}
+	if in.Password != nil {
+		form.AddFieldError("password", "This field can only be set when the snippet is created")
+	}
+	if !form.Valid() {
+		app.apiValidationError(w, form.Validator)
+		return
+	}
+	updated := &models.Snippet{
+		ID:         snippet.ID,
+		Title:      form.Title,
+		Content:    form.Content,
+		Visibility: form.Visibility,
+		Tags:       parseTags(form.Tags),
+		Expires:    snippet.Expires,
+	}
+	if in.Expires != nil {
+		updated.Expires = form.expiresAt(now)
+	}
+	updated.Language, updated.LanguageConfidence = form.language()
+	if err := app.snippets.Update(updated); err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+			return
+		}
+		app.apiServerError(w, err)
+		return
+	}
+	// Report the snippet as it is now stored.
+	stored := *snippet
+	stored.Title, stored.Content, stored.Visibility = updated.Title, updated.Content, updated.Visibility
+	stored.Tags, stored.Expires = updated.Tags, updated.Expires
+	stored.Language, stored.LanguageConfidence = updated.Language, updated.LanguageConfidence
+	app.writeJSON(w, http.StatusOK, apiSnippetResponse{Snippet: app.newAPISnippetDetail(&stored)})
+}
+
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	snippet, ok := app.apiOwnedSnippet(w, r, userID)
+	if !ok {
+		return
+	}
+	if err := app.snippets.Delete(snippet.ID); err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+			return
+		}
+		app.apiServerError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiMethodNotAllowed is used by the router for API paths that exist but
+// don't support the request method. The router has set the Allow header.
+func (app *application) apiMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	allowed := strings.Split(w.Header().Get("Allow"), ", ")
+	sort.Strings(allowed)
+	app.apiError(w, http.StatusMethodNotAllowed, fmt.Sprintf("the %s method is not supported, use %s", r.Method, strings.Join(allowed, ", ")))
+}
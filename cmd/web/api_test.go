@@ -0,0 +1,352 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/models/mock"
+)
+
+func TestAPILoginLimit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	login := func(email string) int {
+		code, _, _ := ts.post(t, "/api/v1/tokens", header, `{"email": "`+email+`", "password": "pa$$word"}`)
+		return code
+	}
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, login("nobody@example.com"), http.StatusUnauthorized)
+	}
+	// The address is refused whatever its case, but others are still let in.
+	assert.Equal(t, login("NoBody@Example.com"), http.StatusTooManyRequests)
+	assert.Equal(t, login("alice@example.com"), http.StatusCreated)
+}
+
+// recordingTokens keeps the scopes and expiry of the last token created.
+type recordingTokens struct {
+	mock.TokenModel
+	scopes  []models.Scope
+	expires time.Time
+}
+
+func (m *recordingTokens) New(userID int, name string, scopes []models.Scope, expires time.Time) (*models.Token, string, error) {
+	m.scopes, m.expires = scopes, expires
+	return m.TokenModel.New(userID, name, scopes, expires)
+}
+
+func TestAPILoginDefaults(t *testing.T) {
+	app := newTestApplication(t)
+	tokens := &recordingTokens{}
+	app.tokens = tokens
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	tests := []struct {
+		name        string
+		body        string
+		wantScopes  string
+		wantExpires time.Time
+	}{
+		{
+			name:        "Defaults",
+			body:        `{"email": "alice@example.com", "password": "pa$$word"}`,
+			wantScopes:  "read",
+			wantExpires: time.Now().UTC().AddDate(0, 0, 30),
+		},
+		{
+			name:       "Asked for",
+			body:       `{"email": "alice@example.com", "password": "pa$$word", "scopes": ["read", "write", "delete"], "expires_days": 0}`,
+			wantScopes: "read,write,delete",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := ts.post(t, "/api/v1/tokens", header, tt.body)
+			assert.Equal(t, code, http.StatusCreated)
+			scopes := make([]string, len(tokens.scopes))
+			for i, scope := range tokens.scopes {
+				scopes[i] = string(scope)
+			}
+			assert.Equal(t, strings.Join(scopes, ","), tt.wantScopes)
+			if tt.wantExpires.IsZero() {
+				assert.Equal(t, tokens.expires.IsZero(), true)
+			} else {
+				diff := tokens.expires.Sub(tt.wantExpires)
+				assert.Equal(t, diff > -time.Minute && diff < time.Minute, true)
+			}
+		})
+	}
+}
+
+func TestAPI(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		token       string
+		contentType string
+		body        string
+		wantCode    int
+		wantBody    string
+	}{
+		{
+			name:     "Login",
+			method:   http.MethodPost,
+			path:     "/api/v1/tokens",
+			body:     `{"email": "alice@example.com", "password": "pa$$word", "name": "laptop"}`,
+			wantCode: http.StatusCreated,
+			wantBody: `{"token":"sb_n3wT0k3n"}`,
+		},
+		{
+			name:     "Login with wrong credentials",
+			method:   http.MethodPost,
+			path:     "/api/v1/tokens",
+			body:     `{"email": "nobody@example.com", "password": "pa$$word"}`,
+			wantCode: http.StatusUnauthorized,
+			wantBody: `{"error":{"status":401,"message":"email or password is incorrect"}}`,
+		},
+		{
+			name:     "Current user",
+			method:   http.MethodGet,
+			path:     "/api/v1/user",
+			token:    "sb_valid",
+			wantCode: http.StatusOK,
+			wantBody: `"email":"alice@example.com"`,
+		},
+		{
+			name:     "Current user without token",
+			method:   http.MethodGet,
+			path:     "/api/v1/user",
+			wantCode: http.StatusUnauthorized,
+			wantBody: `"message":"a valid API token is required"`,
+		},
+		{
+			name:     "Invalid token",
+			method:   http.MethodGet,
+			path:     "/api/v1/snippets/aB3dE5gH7j",
+			token:    "sb_invalid",
+			wantCode: http.StatusUnauthorized,
+			wantBody: `"message":"invalid API token"`,
+		},
+		{
+			name:     "List",
+			method:   http.MethodGet,
+			path:     "/api/v1/snippets?sort=oldest&size=1",
+			wantCode: http.StatusOK,
+			wantBody: `"next":"`,
+		},
+		{
+			name:     "List with invalid sort",
+			method:   http.MethodGet,
+			path:     "/api/v1/snippets?sort=sideways",
+			wantCode: http.StatusBadRequest,
+			wantBody: `"message":"invalid sort order \"sideways\""`,
+		},
+		{
+			name:     "Get",
+			method:   http.MethodGet,
+			path:     "/api/v1/snippets/aB3dE5gH7j",
+			wantCode: http.StatusOK,
+			wantBody: `"content":"An old silent pond...","language":"","visibility":"public"`,
+		},
+		{
+			name:     "Get link",
+			method:   http.MethodGet,
+			path:     "/api/v1/snippets/aB3dE5gH7j",
+			wantCode: http.StatusOK,
+			wantBody: `"url":"https://snippetbox.example/s/aB3dE5gH7j"`,
+		},
+		{
+			name:     "Get private without token",
+			method:   http.MethodGet,
+			path:     "/api/v1/snippets/Zx9Wv8Ut7s",
+			wantCode: http.StatusNotFound,
+			wantBody: `{"error":{"status":404,"message":"the requested resource could not be found"}}`,
+		},
+		{
+			name:     "Get private with token",
+			method:   http.MethodGet,
+			path:     "/api/v1/snippets/Zx9Wv8Ut7s",
+			token:    "sb_valid",
+			wantCode: http.StatusOK,
+			wantBody: `"tags":["poetry"]`,
+		},
+		{
+			name:     "Get burn after reading",
+			method:   http.MethodGet,
+			path:     "/api/v1/snippets/Bu7nAfT3rR",
+			token:    "sb_valid",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Get password protected",
+			method:   http.MethodGet,
+			path:     "/api/v1/snippets/Pr0tEcT3dS",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Create",
+			method:   http.MethodPost,
+			path:     "/api/v1/snippets",
+			token:    "sb_valid",
+			body:     `{"title": "Hello", "content": "package main", "language": "go", "expires": "never", "tags": ["go"]}`,
+			wantCode: http.StatusCreated,
+			wantBody: `"slug":"n3wSn1pp3t"`,
+		},
+		{
+			name:     "Create without token",
+			method:   http.MethodPost,
+			path:     "/api/v1/snippets",
+			body:     `{"title": "Hello", "content": "package main"}`,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Create invalid",
+			method:   http.MethodPost,
+			path:     "/api/v1/snippets",
+			token:    "sb_valid",
+			body:     `{"title": "", "content": "package main", "expires": "tomorrow"}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `"fields":{"expires":"This field must be a number followed by m, h or d, or never","title":"This field cannot be blank"}`,
+		},
+		{
+			name:     "Create with badly-formed JSON",
+			method:   http.MethodPost,
+			path:     "/api/v1/snippets",
+			token:    "sb_valid",
+			body:     `{"title": "Hello",}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `"message":"body contains badly-formed JSON (at character 19)"`,
+		},
+		{
+			name:     "Create with unknown field",
+			method:   http.MethodPost,
+			path:     "/api/v1/snippets",
+			token:    "sb_valid",
+			body:     `{"title": "Hello", "colour": "red"}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `"message":"body contains unknown field \"colour\""`,
+		},
+		{
+			name:     "Create with wrong type",
+			method:   http.MethodPost,
+			path:     "/api/v1/snippets",
+			token:    "sb_valid",
+			body:     `{"title": 42}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `"message":"body contains incorrect JSON type for field \"title\""`,
+		},
+		{
+			name:        "Create with wrong content type",
+			method:      http.MethodPost,
+			path:        "/api/v1/snippets",
+			token:       "sb_valid",
+			contentType: "application/x-www-form-urlencoded",
+			body:        `title=Hello`,
+			wantCode:    http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "Create too large",
+			method:   http.MethodPost,
+			path:     "/api/v1/snippets",
+			token:    "sb_valid",
+			body:     `{"content": "` + strings.Repeat("a", maxAPIBodySize) + `"}`,
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "Update",
+			method:   http.MethodPatch,
+			path:     "/api/v1/snippets/aB3dE5gH7j",
+			token:    "sb_valid",
+			body:     `{"title": "A new pond"}`,
+			wantCode: http.StatusOK,
+			wantBody: `"title":"A new pond","content":"An old silent pond..."`,
+		},
+		{
+			name:     "Update burn after reading",
+			method:   http.MethodPatch,
+			path:     "/api/v1/snippets/aB3dE5gH7j",
+			token:    "sb_valid",
+			body:     `{"burn_after_reading": true}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `"burn_after_reading":"This field can only be set when the snippet is created"`,
+		},
+		{
+			name:     "Update someone else's",
+			method:   http.MethodPatch,
+			path:     "/api/v1/snippets/Qw3rTy7uIo",
+			token:    "sb_valid",
+			body:     `{"title": "Mine now"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Update encrypted",
+			method:   http.MethodPatch,
+			path:     "/api/v1/snippets/3nCrYpT3dX",
+			token:    "sb_valid",
+			body:     `{"title": "Launch codes"}`,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:     "Delete",
+			method:   http.MethodDelete,
+			path:     "/api/v1/snippets/aB3dE5gH7j",
+			token:    "sb_valid",
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "Delete missing",
+			method:   http.MethodDelete,
+			path:     "/api/v1/snippets/aaaaaaaaaa",
+			token:    "sb_valid",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Method not allowed",
+			method:   http.MethodPut,
+			path:     "/api/v1/snippets/aB3dE5gH7j",
+			wantCode: http.StatusMethodNotAllowed,
+			wantBody: `"message":"the PUT method is not supported, use DELETE, GET, OPTIONS, PATCH"`,
+		},
+		{
+			name:     "Unknown endpoint",
+			method:   http.MethodGet,
+			path:     "/api/v1/widgets",
+			wantCode: http.StatusNotFound,
+			wantBody: `"status":404`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.contentType != "" {
+				header.Set("Content-Type", tt.contentType)
+			} else if tt.body != "" {
+				header.Set("Content-Type", "application/json")
+			}
+			code, headers, body := ts.request(t, tt.method, tt.path, header, tt.body)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, string(body), tt.wantBody)
+			if code != http.StatusNoContent {
+				assert.Equal(t, headers.Get("Content-Type"), "application/json")
+			}
+		})
+	}
+}
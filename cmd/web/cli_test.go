@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/cli"
+)
+
+// The snippet command is tested here so that it can talk to the real routes.
+
+func TestCLI(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	configPath := filepath.Join(t.TempDir(), "snippetbox", "config.json")
+	run := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := cli.Run(append([]string{"-config", configPath}, args...), strings.NewReader(stdin), &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	code, _, stderr := run("", "list")
+	assert.Equal(t, code, 1)
+	assert.StringContains(t, stderr, "not logged in")
+
+	code, _, stderr = run("nobody@example.com\nwrong\n", "login", "-server", ts.URL, "-insecure")
+	assert.Equal(t, code, 1)
+	assert.StringContains(t, stderr, "email or password is incorrect")
+
+	code, stdout, _ := run("alice@example.com\npa$$word\n", "login", "-server", ts.URL, "-insecure")
+	assert.Equal(t, code, 0)
+	assert.StringContains(t, stdout, "Logged in to "+ts.URL)
+	b, err := os.ReadFile(configPath)
+	assert.NilError(t, err)
+	assert.StringContains(t, string(b), `"token": "sb_n3wT0k3n"`)
+	info, err := os.Stat(configPath)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0o600))
+
+	// The mock token model only knows its own token.
+	code, _, _ = run("", "login", "-server", ts.URL, "-insecure", "-token", "sb_valid")
+	assert.Equal(t, code, 0)
+
+	tests := []struct {
+		name       string
+		stdin      string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "Create",
+			stdin:      "echo hello",
+			args:       []string{"create", "-language", "shell", "-expires", "1d"},
+			wantStdout: "https://snippetbox.example/s/n3wSn1pp3t\n",
+		},
+		{
+			name:       "Create invalid",
+			stdin:      "echo hello",
+			args:       []string{"create", "-expires", "soon"},
+			wantCode:   1,
+			wantStderr: "expires: This field must be a number followed by m, h or d, or never",
+		},
+		{
+			name:       "Get",
+			args:       []string{"get", "aB3dE5gH7j"},
+			wantStdout: "An old silent pond...",
+		},
+		{
+			name:       "Get by URL",
+			args:       []string{"get", ts.URL + "/s/aB3dE5gH7j"},
+			wantStdout: "An old silent pond...",
+		},
+		{
+			name:       "Get locked",
+			args:       []string{"get", "Pr0tEcT3dS"},
+			wantCode:   1,
+			wantStderr: "locked with a password",
+		},
+		{
+			name:       "Get encrypted",
+			args:       []string{"get", "3nCrYpT3dX"},
+			wantCode:   1,
+			wantStderr: "snippet 3nCrYpT3dX is encrypted",
+		},
+		{
+			name:       "Get missing",
+			args:       []string{"get", "aaaaaaaaaa"},
+			wantCode:   1,
+			wantStderr: "server replied 404: the requested resource could not be found",
+		},
+		{
+			name:       "List",
+			args:       []string{"list"},
+			wantStdout: "aB3dE5gH7j  public",
+		},
+		{
+			name: "Delete",
+			args: []string{"delete", "aB3dE5gH7j"},
+		},
+		{
+			name:       "Delete someone else's",
+			args:       []string{"delete", "Qw3rTy7uIo"},
+			wantCode:   1,
+			wantStderr: "only the owner of a snippet can change it",
+		},
+		{
+			name:     "Unknown command",
+			args:     []string{"publish"},
+			wantCode: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := run(tt.stdin, tt.args...)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, stdout, tt.wantStdout)
+			assert.StringContains(t, stderr, tt.wantStderr)
+		})
+	}
+
+	code, _, _ = run("", "logout")
+	assert.Equal(t, code, 0)
+	code, _, stderr = run("", "delete", "aB3dE5gH7j")
+	assert.Equal(t, code, 1)
+	assert.StringContains(t, stderr, "not logged in")
+}
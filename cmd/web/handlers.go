@@ -3,11 +3,18 @@ package main
 import (
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/xyedo/snippetbox/internal/detect"
+	"github.com/xyedo/snippetbox/internal/diff"
 	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/syntax"
 	"github.com/xyedo/snippetbox/internal/validator"
 )
 
@@ -17,76 +24,647 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 		app.serverError(w, err)
 		return
 	}
+	tags, err := app.snippets.TagCloud(tagCloudSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 	data := app.newTemplateData(r)
 	data.Snippets = snippets
+	data.TagCloud = newTagCloud(tags)
 	app.render(w, http.StatusOK, "home.tmpl", data)
 
 }
 
 func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
-	params := httprouter.ParamsFromContext(r.Context())
-	id, err := strconv.Atoi(params.ByName("id"))
-	if err != nil || id < 1 {
-		app.notFound(w)
+	snippet, ok := app.lookupSnippet(w, r)
+	if !ok {
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	if !app.isUnlocked(r, snippet) {
+		data.Form = snippetUnlockForm{}
+		w.Header().Set("Cache-Control", "no-store")
+		app.render(w, http.StatusOK, "unlock.tmpl", data)
+		return
+	}
+	// Reading a burn-after-reading snippet deletes it, so ask for a
+	// confirmation first. Link previews only ever GET this page.
+	if snippet.BurnAfterReading && !snippet.OwnedBy(app.authenticatedUserID(r)) {
+		w.Header().Set("Cache-Control", "no-store")
+		app.render(w, http.StatusOK, "burn.tmpl", data)
 		return
 	}
-	snippet, err := app.snippets.Get(id)
+	app.render(w, http.StatusOK, "view.tmpl", data)
+}
+
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+	serveSnippetContent(w, r, snippet)
+}
+
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": downloadName(snippet)}))
+	serveSnippetContent(w, r, snippet)
+}
+
+func (app *application) snippetBurnPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.lookupSnippet(w, r)
+	if !ok {
+		return
+	}
+	if !snippet.BurnAfterReading || snippet.OwnedBy(app.authenticatedUserID(r)) || !app.isUnlocked(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/s/%s", snippet.Slug), http.StatusSeeOther)
+		return
+	}
+	snippet, err := app.snippets.Consume(snippet.ID)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			app.notFound(w)
 			return
+		}
+		app.serverError(w, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Flash = "This snippet has now been deleted. Copy it before you leave this page."
+	w.Header().Set("Cache-Control", "no-store")
+	app.render(w, http.StatusOK, "view.tmpl", data)
+}
+
+type snippetUnlockForm struct {
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
 
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.lookupSnippet(w, r)
+	if !ok {
+		return
+	}
+	if app.isUnlocked(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/s/%s", snippet.Slug), http.StatusSeeOther)
+		return
+	}
+	var form snippetUnlockForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	w.Header().Set("Cache-Control", "no-store")
+
+	limiterKey := strconv.Itoa(snippet.ID)
+	if app.unlockLimiter.Exceeded(limiterKey) {
+		form.AddNonFieldError("Too many failed attempts. Please try again later.")
+		data.Form = form
+		app.render(w, http.StatusTooManyRequests, "unlock.tmpl", data)
+		return
+	}
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
+	if !form.Valid() {
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "unlock.tmpl", data)
+		return
+	}
+	err = app.snippets.Unlock(snippet.ID, form.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			// Count the failed attempt against the snippet.
+			app.unlockLimiter.Allow(limiterKey)
+			form.AddFieldError("password", "Password is incorrect")
+			data.Form = form
+			app.render(w, http.StatusUnprocessableEntity, "unlock.tmpl", data)
+			return
+		}
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+			return
 		}
 		app.serverError(w, err)
 		return
+	}
+	app.sessionManager.Put(r.Context(), unlockSessionKey(snippet.ID), time.Now().Add(unlockLifetime).Unix())
+	http.Redirect(w, r, fmt.Sprintf("/s/%s", snippet.Slug), http.StatusSeeOther)
+}
 
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
 	}
 	data := app.newTemplateData(r)
 	data.Snippet = snippet
-	app.render(w, http.StatusOK, "view.tmpl", data)
+	data.Revisions = revisions
+	app.render(w, http.StatusOK, "history.tmpl", data)
+}
+
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+	qs := r.URL.Query()
+	from, err := strconv.Atoi(qs.Get("from"))
+	if err != nil || from < 1 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	to, err := strconv.Atoi(qs.Get("to"))
+	if err != nil || to < 1 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	var revisions [2]*models.Revision
+	for i, number := range []int{from, to} {
+		revisions[i], err = app.snippets.Revision(snippet.ID, number)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+				return
+			}
+			app.serverError(w, err)
+			return
+		}
+	}
+	hunks, err := diff.Unified(revisions[0].Content, revisions[1].Content, 3)
+	if err != nil && !errors.Is(err, diff.ErrTooDifferent) {
+		app.serverError(w, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Diff = &revisionDiff{
+		From:         revisions[0],
+		To:           revisions[1],
+		Hunks:        hunks,
+		TooDifferent: err != nil,
+	}
+	app.render(w, http.StatusOK, "diff.tmpl", data)
+}
+
+func (app *application) tagView(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	tag := params.ByName("name")
+	if !validator.Matches(tag, validator.TagRX) {
+		app.notFound(w)
+		return
+	}
+	page, ok := app.readPage(w, r)
+	if !ok {
+		return
+	}
+	snippets, err := app.snippets.ByTag(tag, (page-1)*app.pageSize, app.pageSize+1)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	snippets, pages, ok := app.paginate(w, snippets, page)
+	if !ok {
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Tag = tag
+	data.Snippets = snippets
+	data.Pagination = pages
+	app.render(w, http.StatusOK, "tag.tmpl", data)
+}
+
+// maxPageSize is the largest page size the archive can be asked for.
+const maxPageSize = 100
+
+func (app *application) snippetArchive(w http.ResponseWriter, r *http.Request) {
+	page, cursor, err := app.parseArchiveQuery(r.URL.Query())
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	snippets, err := app.archive(page, cursor)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+	data.Archive = page
+	app.render(w, http.StatusOK, "archive.tmpl", data)
+}
+
+// parseArchiveQuery reads the order, page size and position of an archive
+// page from the sort, size, after and before query parameters.
+func (app *application) parseArchiveQuery(query url.Values) (*archivePage, models.Cursor, error) {
+	page := &archivePage{Order: models.OrderNewest, Size: app.pageSize}
+	if s := query.Get("sort"); s != "" {
+		page.Order = models.SnippetOrder(s)
+	}
+	if !validator.PermittedValue(page.Order, models.OrderNewest, models.OrderOldest, models.OrderExpiring) {
+		return nil, models.Cursor{}, fmt.Errorf("invalid sort order %q", page.Order)
+	}
+	if s := query.Get("size"); s != "" {
+		size, err := strconv.Atoi(s)
+		if err != nil || size < 1 || size > maxPageSize {
+			return nil, models.Cursor{}, fmt.Errorf("size must be between 1 and %d", maxPageSize)
+		}
+		page.Size = size
+	}
+	var cursor models.Cursor
+	var err error
+	if s := query.Get("after"); s != "" {
+		cursor, err = parseCursor(s)
+	} else if s := query.Get("before"); s != "" {
+		cursor, err = parseCursor(s)
+		cursor.Before = true
+	}
+	if err != nil {
+		return nil, models.Cursor{}, err
+	}
+	return page, cursor, nil
+}
+
+// archive fetches the snippets of an archive page and fills in the cursors
+// of its neighbours.
+func (app *application) archive(page *archivePage, cursor models.Cursor) ([]*models.Snippet, error) {
+	// One extra snippet tells whether there is another page in the
+	// direction we're going.
+	snippets, err := app.snippets.Archive(page.Order, cursor, page.Size+1)
+	if err != nil {
+		return nil, err
+	}
+	more := len(snippets) > page.Size
+	hasPrev, hasNext := !cursor.IsZero(), more
+	if cursor.Before {
+		if more {
+			snippets = snippets[1:]
+		}
+		hasPrev, hasNext = more, true
+	} else if more {
+		snippets = snippets[:page.Size]
+	}
+	if len(snippets) > 0 {
+		if hasPrev {
+			page.Prev = formatCursor(page.Order.Cursor(snippets[0]))
+		}
+		if hasNext {
+			page.Next = formatCursor(page.Order.Cursor(snippets[len(snippets)-1]))
+		}
+	}
+	return snippets, nil
+}
+
+// maxQueryLength is the longest search query accepted, in characters.
+const maxQueryLength = 200
+
+func (app *application) searchView(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if !validator.MaxChars(query, maxQueryLength) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	page, ok := app.readPage(w, r)
+	if !ok {
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Query = query
+	if query == "" {
+		app.render(w, http.StatusOK, "search.tmpl", data)
+		return
+	}
+	snippets, err := app.snippets.Search(query, app.authenticatedUserID(r), (page-1)*app.pageSize, app.pageSize+1)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	snippets, pages, ok := app.paginate(w, snippets, page)
+	if !ok {
+		return
+	}
+	rx := termsRX(searchTerms(query))
+	results := make([]*searchResult, 0, len(snippets))
+	for _, s := range snippets {
+		result := &searchResult{Snippet: s, Title: highlight(s.Title, rx)}
+		// Ciphertext isn't worth showing.
+		if !s.Encrypted {
+			result.Excerpt = excerpt(s.Content, rx)
+		}
+		results = append(results, result)
+	}
+	data.Results = results
+	data.Pagination = pages
+	app.render(w, http.StatusOK, "search.tmpl", data)
 }
 
 type snippetCreateForm struct {
-	Title               string `form:"title"`
-	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
+	Title   string `form:"title"`
+	Content string `form:"content"`
+	// Language is empty to have it detected, or plainText.
+	Language string `form:"language"`
+	// Expires counts ExpiresUnit, unless that is "date" (see ExpiresAt) or
+	// "never".
+	Expires             int               `form:"expires"`
+	ExpiresUnit         string            `form:"expires_unit"`
+	ExpiresAt           string            `form:"expires_at"`
+	Visibility          models.Visibility `form:"visibility"`
+	BurnAfterReading    bool              `form:"burn"`
+	Password            string            `form:"password"`
+	Encrypted           bool              `form:"encrypted"`
+	Tags                string            `form:"tags"`
 	validator.Validator `form:"-"`
 }
 
+// expiryUnits are the units the expiry amount of the snippet forms can be
+// given in.
+var expiryUnits = map[string]time.Duration{
+	"minutes": time.Minute,
+	"hours":   time.Hour,
+	"days":    24 * time.Hour,
+}
+
+// expiresAtLayout is the format of <input type='datetime-local'> values.
+const expiresAtLayout = "2006-01-02T15:04"
+
+const maxTags = 5
+
+// maxContentSize is the most snippet content, in bytes, that the TEXT
+// column holding it can store.
+const maxContentSize = 65535
+
+// plainText is the language field value that turns highlighting and
+// detection off.
+const plainText = "text"
+
+// validate checks the fields shared by the create and edit snippet forms.
+// Snippets may not expire more than maxExpiry after now.
+func (form *snippetCreateForm) validate(now time.Time, maxExpiry time.Duration) {
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This filed cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(len(form.Content) <= maxContentSize, "content", fmt.Sprintf("This field cannot be more than %d bytes long", maxContentSize))
+	form.CheckField(validator.PermittedValue(form.Language, append(syntax.Names(), plainText)...), "language", "This field must be one of the listed languages")
+	form.validateExpiry(now, maxExpiry)
+	tags := parseTags(form.Tags)
+	form.CheckField(validator.MaxItems(tags, maxTags), "tags", fmt.Sprintf("This field cannot have more than %d tags", maxTags))
+	form.CheckField(validator.AllMatch(tags, validator.TagRX), "tags", "Tags can only contain letters, digits, dots and dashes, and be up to 32 characters long")
+	form.CheckField(validator.PermittedValue(form.Visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must be public, unlisted, or private")
+	if form.Password != "" {
+		form.CheckField(validator.MinChars(form.Password, 8), "password", "This field must be at least 8 characters long")
+	}
+	// Encrypted content is produced by the browser, so anything else means
+	// the plaintext was about to be submitted by mistake.
+	if form.Encrypted && validator.NotBlank(form.Content) {
+		form.CheckField(models.IsCiphertext(form.Content), "content", "This field must be encrypted in your browser; make sure JavaScript is enabled")
+	}
+}
+
+func (form *snippetCreateForm) validateExpiry(now time.Time, maxExpiry time.Duration) {
+	tooFar := fmt.Sprintf("This field cannot be more than %s away", humanDuration(maxExpiry))
+	switch form.ExpiresUnit {
+	case "never":
+	case "date":
+		expires, err := time.ParseInLocation(expiresAtLayout, form.ExpiresAt, time.UTC)
+		if err != nil {
+			form.AddFieldError("expires", "This field must be a valid date and time")
+			return
+		}
+		form.CheckField(expires.After(now), "expires", "This field must be in the future")
+		form.CheckField(expires.Sub(now) <= maxExpiry, "expires", tooFar)
+	default:
+		unit, ok := expiryUnits[form.ExpiresUnit]
+		if !ok {
+			form.AddFieldError("expires", "This field must be in minutes, hours, days, on a date, or never")
+			return
+		}
+		form.CheckField(form.Expires > 0, "expires", "This field must be a positive number")
+		form.CheckField(time.Duration(form.Expires) <= maxExpiry/unit, "expires", tooFar)
+	}
+}
+
+// language returns the language a snippet submitted through a valid form is
+// highlighted as, detecting it from the content unless the author chose one,
+// and the confidence of the detection.
+func (form *snippetCreateForm) language() (string, float64) {
+	switch form.Language {
+	case plainText:
+		return "", 0
+	case "":
+		// There is nothing to detect in ciphertext.
+		if form.Encrypted {
+			return "", 0
+		}
+		guess := detect.Detect(form.Content)
+		return guess.Language, guess.Confidence
+	}
+	return form.Language, 0
+}
+
+// expiresAt returns when a snippet submitted through a valid form at now
+// expires, or the zero time if it never does.
+func (form *snippetCreateForm) expiresAt(now time.Time) time.Time {
+	switch form.ExpiresUnit {
+	case "never":
+		return time.Time{}
+	case "date":
+		expires, _ := time.ParseInLocation(expiresAtLayout, form.ExpiresAt, time.UTC)
+		return expires
+	}
+	return now.Add(time.Duration(form.Expires) * expiryUnits[form.ExpiresUnit])
+}
+
 func (app *application) snippetCreateView(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
-	data.Form = snippetCreateForm{}
+	data.Form = snippetCreateForm{
+		Expires:     7,
+		ExpiresUnit: "days",
+		Visibility:  models.VisibilityPublic,
+	}
 	app.render(w, http.StatusOK, "create.tmpl", data)
 }
 func (app *application) createSnippetPost(w http.ResponseWriter, r *http.Request) {
-
-	var form snippetCreateForm
+	// Older clients send the expiry in days and no visibility.
+	form := snippetCreateForm{
+		ExpiresUnit: "days",
+		Visibility:  models.VisibilityPublic,
+	}
 	err := app.decodePostForm(r, &form)
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This filed cannot be more than 100 characters long")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
-	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7, or 365")
-
+	now := time.Now().UTC()
+	form.validate(now, app.maxExpiry)
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
 		app.render(w, http.StatusUnprocessableEntity, "create.tmpl", data)
 		return
 	}
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	snippet := &models.Snippet{
+		UserID:           app.authenticatedUserID(r),
+		Title:            form.Title,
+		Content:          form.Content,
+		Visibility:       form.Visibility,
+		BurnAfterReading: form.BurnAfterReading,
+		Encrypted:        form.Encrypted,
+		Tags:             parseTags(form.Tags),
+		Expires:          form.expiresAt(now),
+	}
+	snippet.Language, snippet.LanguageConfidence = form.language()
+	err = app.snippets.Insert(snippet, form.Password)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 	app.sessionManager.Put(r.Context(), "flash", "Snippet succesfully created!")
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	http.Redirect(w, r, fmt.Sprintf("/s/%s", snippet.Slug), http.StatusSeeOther)
 
 }
 
+func (app *application) snippetEditView(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	// The server can't re-encrypt the content, so encrypted snippets
+	// can't be edited.
+	if snippet.Encrypted {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	form := snippetCreateForm{
+		Title:       snippet.Title,
+		Content:     snippet.Content,
+		Language:    languageChoice(snippet),
+		ExpiresUnit: "never",
+		Visibility:  snippet.Visibility,
+		Tags:        strings.Join(snippet.Tags, " "),
+	}
+	// Keep the current expiry unless the owner picks a new one.
+	if !snippet.NeverExpires() {
+		form.ExpiresUnit = "date"
+		form.ExpiresAt = snippet.Expires.UTC().Format(expiresAtLayout)
+	}
+	data.Form = form
+	app.render(w, http.StatusOK, "edit.tmpl", data)
+}
+
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	// The server can't re-encrypt the content, so encrypted snippets
+	// can't be edited.
+	if snippet.Encrypted {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form := snippetCreateForm{
+		Language:    languageChoice(snippet),
+		ExpiresUnit: "days",
+		Visibility:  snippet.Visibility,
+	}
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	now := time.Now().UTC()
+	form.validate(now, app.maxExpiry)
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "edit.tmpl", data)
+		return
+	}
+	updated := &models.Snippet{
+		ID:         snippet.ID,
+		Title:      form.Title,
+		Content:    form.Content,
+		Visibility: form.Visibility,
+		Tags:       parseTags(form.Tags),
+		Expires:    form.expiresAt(now),
+	}
+	updated.Language, updated.LanguageConfidence = form.language()
+	err = app.snippets.Update(updated)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully updated!")
+	http.Redirect(w, r, fmt.Sprintf("/s/%s", snippet.Slug), http.StatusSeeOther)
+}
+
+type snippetLanguageForm struct {
+	Language            string `form:"language"`
+	validator.Validator `form:"-"`
+}
+
+// snippetLanguagePost lets the owner override the language a snippet was
+// detected as, or have it detected again.
+func (app *application) snippetLanguagePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	var form snippetLanguageForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	// The view page only offers valid choices.
+	if !validator.PermittedValue(form.Language, append(syntax.Names(), plainText)...) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	choice := snippetCreateForm{Language: form.Language, Content: snippet.Content, Encrypted: snippet.Encrypted}
+	language, confidence := choice.language()
+	err = app.snippets.SetLanguage(snippet.ID, language, confidence)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Language successfully updated!")
+	http.Redirect(w, r, fmt.Sprintf("/s/%s", snippet.Slug), http.StatusSeeOther)
+}
+
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+			return
+		}
+		app.serverError(w, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully deleted!")
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
 type userSignupForm struct {
 	Name                string `form:"name"`
 	Email               string `form:"email"`
@@ -120,7 +698,7 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 		app.render(w, http.StatusUnprocessableEntity, "signup.tmpl", data)
 		return
 	}
-	err = app.users.Insert(form.Name, form.Email, form.Password)
+	id, err := app.users.Insert(form.Name, form.Email, form.Password)
 	if err != nil {
 		if errors.Is(err, models.ErrDuplicateEmail) {
 			form.AddFieldError("email", "Email address is already in use")
@@ -132,7 +710,13 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 		app.serverError(w, err)
 		return
 	}
-	app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. Please log in")
+	// The account exists even if the mail can't be sent; a new link can be
+	// requested from the account page.
+	err = app.sendVerification(&models.User{ID: id, Name: form.Name, Email: form.Email})
+	if err != nil {
+		app.errorLog.Printf("verification email to %s: %v", form.Email, err)
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. We've emailed you a link to verify your address. Please log in")
 	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
 
 }
@@ -210,26 +794,168 @@ func (app *application) aboutView(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *application) accountView(w http.ResponseWriter, r *http.Request) {
+	data, ok := app.accountData(w, r)
+	if !ok {
+		return
+	}
+	app.render(w, http.StatusOK, "account.tmpl", data)
+}
+
+// accountData loads what the account page shows: the user, their snippets,
+// their API tokens and their webhooks with the latest deliveries, along with
+// empty forms to create tokens and webhooks. When that fails, the response
+// has already been written and ok is false.
+func (app *application) accountData(w http.ResponseWriter, r *http.Request) (data *templateData, ok bool) {
 	id := app.sessionManager.GetInt(r.Context(), "authenticateUserID")
 	if id == 0 {
 		http.Redirect(w, r, "/user/login", http.StatusUnauthorized)
-		return
+		return nil, false
 	}
 	user, err := app.users.Get(id)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			app.sessionManager.Remove(r.Context(), "authenticateUserID")
 			http.Redirect(w, r, "/user/login", http.StatusUnauthorized)
-			return
+			return nil, false
 		}
 		app.serverError(w, err)
-		return
+		return nil, false
 	}
-	data := app.newTemplateData(r)
+	snippets, err := app.snippets.ByUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return nil, false
+	}
+	tokens, err := app.tokens.ByUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return nil, false
+	}
+	webhooks, err := app.webhooks.ByUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return nil, false
+	}
+	deliveries, err := app.webhooks.Deliveries(id, deliveryLogSize)
+	if err != nil {
+		app.serverError(w, err)
+		return nil, false
+	}
+	data = app.newTemplateData(r)
 	data.User = user
+	data.Snippets = snippets
+	data.Tokens = tokens
+	data.Webhooks = webhooks
+	data.Deliveries = deliveries
+	data.Form = newTokenCreateForm()
+	data.WebhookForm = webhookCreateForm{}
+	return data, true
+}
+
+type tokenCreateForm struct {
+	Name                string `form:"name"`
+	Read                bool   `form:"read"`
+	Write               bool   `form:"write"`
+	Delete              bool   `form:"delete"`
+	ExpiresDays         int    `form:"expires_days"`
+	validator.Validator `form:"-"`
+}
+
+// newTokenCreateForm returns the form as first shown, for a token that can
+// read and write but not delete and expires after 90 days.
+func newTokenCreateForm() tokenCreateForm {
+	return tokenCreateForm{Read: true, Write: true, ExpiresDays: 90}
+}
+
+func (form *tokenCreateForm) scopes() []models.Scope {
+	scopes := []models.Scope{}
+	if form.Read {
+		scopes = append(scopes, models.ScopeRead)
+	}
+	if form.Write {
+		scopes = append(scopes, models.ScopeWrite)
+	}
+	if form.Delete {
+		scopes = append(scopes, models.ScopeDelete)
+	}
+	return scopes
+}
+
+// maxTokenLifetime is the most days a token can be valid for, other than
+// forever.
+const maxTokenLifetime = 365
+
+// validateToken checks the details of a new API token. expiresDays of zero
+// asks for a token that never expires.
+func validateToken(v *validator.Validator, name string, scopes []models.Scope, expiresDays int) {
+	v.CheckField(validator.NotBlank(name), "name", "This field cannot be blank")
+	v.CheckField(validator.MaxChars(name, 100), "name", "This field cannot be more than 100 characters long")
+	v.CheckField(len(scopes) > 0, "scopes", "Pick at least one scope")
+	for _, scope := range scopes {
+		v.CheckField(validator.PermittedValue(scope, models.Scopes...), "scopes", "This field must only contain read, write and delete")
+	}
+	v.CheckField(expiresDays >= 0 && expiresDays <= maxTokenLifetime, "expires_days", fmt.Sprintf("This field must be between 0 and %d", maxTokenLifetime))
+}
+
+// tokenExpiry returns when a token created at now expires after days, or the
+// zero time for a token that never expires.
+func tokenExpiry(now time.Time, days int) time.Time {
+	if days == 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, days)
+}
+
+// tokenCreatePost creates an API token and shows it on the account page.
+// The token is rendered straight away rather than after a redirect, since
+// it must not be kept in the session, and it can't be shown again later.
+func (app *application) tokenCreatePost(w http.ResponseWriter, r *http.Request) {
+	var form tokenCreateForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	validateToken(&form.Validator, form.Name, form.scopes(), form.ExpiresDays)
+
+	data, ok := app.accountData(w, r)
+	if !ok {
+		return
+	}
+	if !form.Valid() {
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "account.tmpl", data)
+		return
+	}
+	token, plaintext, err := app.tokens.New(app.authenticatedUserID(r), form.Name, form.scopes(), tokenExpiry(time.Now().UTC(), form.ExpiresDays))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	data.Tokens = append([]*models.Token{token}, data.Tokens...)
+	data.NewToken = plaintext
 	app.render(w, http.StatusOK, "account.tmpl", data)
 }
 
+func (app *application) tokenDeletePost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	err = app.tokens.Delete(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+			return
+		}
+		app.serverError(w, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "API token successfully revoked!")
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
 type updatePasswordPost struct {
 	CurrentPassword     string `form:"currentPassword"`
 	NewPassword         string `form:"newPassword"`
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"html"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/models"
 )
 
 func TestViewHome(t *testing.T) {
@@ -16,6 +21,7 @@ func TestViewHome(t *testing.T) {
 	code, _, body := ts.get(t, "/")
 	assert.Equal(t, code, http.StatusOK)
 	assert.StringContains(t, string(body), "<h2>Latest Snippets</h2>")
+	assert.StringContains(t, string(body), "<a href='/tag/poetry' class='tag weight-5' title='2 snippets'>poetry</a>")
 }
 func TestSnippetView(t *testing.T) {
 	app := newTestApplication(t)
@@ -35,11 +41,86 @@ func TestSnippetView(t *testing.T) {
 			wantCode: http.StatusOK,
 			wantBody: "An old silent pond...",
 		},
+		{
+			name:     "Shows author",
+			path:     "/snippet/view/1",
+			wantCode: http.StatusOK,
+			wantBody: "by Alice",
+		},
+		{
+			name:     "Shows tags",
+			path:     "/snippet/view/1",
+			wantCode: http.StatusOK,
+			wantBody: "<a href='/tag/haiku' class='tag'>haiku</a>",
+		},
+		{
+			name:     "Shows countdown",
+			path:     "/snippet/view/1",
+			wantCode: http.StatusOK,
+			wantBody: "data-countdown>Expires in",
+		},
+		{
+			name:     "Plain text",
+			path:     "/s/aB3dE5gH7j",
+			wantCode: http.StatusOK,
+			wantBody: "<pre><code>An old silent pond...</code></pre>",
+		},
+		{
+			name:     "Highlights code",
+			path:     "/s/Qw3rTy7uIo",
+			wantCode: http.StatusOK,
+			wantBody: "<pre><code class='language-python'>Over the wintry forest, winds howl <span class='hl-keyword'>in</span> rage...</code></pre>",
+		},
+		{
+			name:     "Shows language",
+			path:     "/s/Qw3rTy7uIo",
+			wantCode: http.StatusOK,
+			wantBody: "<span>Python</span>",
+		},
+		{
+			name:     "Shows detected language",
+			path:     "/s/D3t3cT3dGo",
+			wantCode: http.StatusOK,
+			wantBody: "<span>Go <small>(detected, 87% sure)</small></span>",
+		},
+		{
+			name:     "Never expires",
+			path:     "/s/Qw3rTy7uIo",
+			wantCode: http.StatusOK,
+			wantBody: "Never expires",
+		},
 		{
 			name:     "Non-existent ID",
 			path:     "/snippet/view/2",
 			wantCode: http.StatusNotFound,
 		},
+		{
+			name:     "Private snippet of someone else",
+			path:     "/snippet/view/4",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Valid slug",
+			path:     "/s/aB3dE5gH7j",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond...",
+		},
+		{
+			name:     "Slug in place of ID",
+			path:     "/snippet/view/aB3dE5gH7j",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond...",
+		},
+		{
+			name:     "Non-existent slug",
+			path:     "/s/aaaaaaaaaa",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Private slug of someone else",
+			path:     "/s/Zx9Wv8Ut7s",
+			wantCode: http.StatusNotFound,
+		},
 		{
 			name:     "Negative ID",
 			path:     "/snippet/view/-1",
@@ -72,6 +153,259 @@ func TestSnippetView(t *testing.T) {
 		})
 	}
 }
+func TestTagView(t *testing.T) {
+	app := newTestApplication(t)
+	app.pageSize = 1
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody []string
+	}{
+		{
+			name:     "First page",
+			path:     "/tag/poetry",
+			wantCode: http.StatusOK,
+			wantBody: []string{"An old silent pond", "<a href='/tag/poetry?page=2'>Older &rarr;</a>"},
+		},
+		{
+			name:     "Last page",
+			path:     "/tag/poetry?page=2",
+			wantCode: http.StatusOK,
+			wantBody: []string{"Over the wintry forest", "<a href='/tag/poetry?page=1'>&larr; Newer</a>"},
+		},
+		{
+			// The private snippet tagged poetry is never listed.
+			name:     "Past the end",
+			path:     "/tag/poetry?page=3",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Unused tag",
+			path:     "/tag/prose",
+			wantCode: http.StatusOK,
+			wantBody: []string{"There are no snippets with this tag."},
+		},
+		{
+			name:     "Invalid page",
+			path:     "/tag/poetry?page=0",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid tag",
+			path:     "/tag/Poetry",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.path)
+			assert.Equal(t, code, tt.wantCode)
+			for _, want := range tt.wantBody {
+				assert.StringContains(t, string(body), want)
+			}
+		})
+	}
+}
+
+func TestSnippetArchive(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantCode   int
+		wantBody   []string
+		unwantBody []string
+	}{
+		{
+			name:       "Newest first",
+			path:       "/snippets",
+			wantCode:   http.StatusOK,
+			wantBody:   []string{"Over the wintry forest", "An old silent pond", "class='current'>Newest</a>"},
+			unwantBody: []string{"Dear diary", "The door code", "Launch codes", "&larr; Previous", "Next &rarr;"},
+		},
+		{
+			name:       "Expiring soon",
+			path:       "/snippets?sort=expiring",
+			wantCode:   http.StatusOK,
+			wantBody:   []string{"An old silent pond"},
+			unwantBody: []string{"Over the wintry forest"},
+		},
+		{
+			name:     "Invalid sort",
+			path:     "/snippets?sort=random",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Page size too large",
+			path:     "/snippets?size=101",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid cursor",
+			path:     "/snippets?after=yesterday",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.path)
+			assert.Equal(t, code, tt.wantCode)
+			for _, want := range tt.wantBody {
+				assert.StringContains(t, string(body), want)
+			}
+			for _, unwant := range tt.unwantBody {
+				assert.Equal(t, strings.Contains(string(body), unwant), false)
+			}
+		})
+	}
+
+	t.Run("Cursors", func(t *testing.T) {
+		linkRX := func(label string) *regexp.Regexp {
+			return regexp.MustCompile(`<a href='([^']+)'>` + regexp.QuoteMeta(label) + `</a>`)
+		}
+		follow := func(t *testing.T, body []byte, label string) []byte {
+			t.Helper()
+			m := linkRX(label).FindSubmatch(body)
+			if m == nil {
+				t.Fatalf("no %q link", label)
+			}
+			code, _, body := ts.get(t, html.UnescapeString(string(m[1])))
+			assert.Equal(t, code, http.StatusOK)
+			return body
+		}
+
+		_, _, body := ts.get(t, "/snippets?sort=oldest&size=1")
+		assert.StringContains(t, string(body), "An old silent pond")
+		assert.Equal(t, linkRX("&larr; Previous").Match(body), false)
+
+		body = follow(t, body, "Next &rarr;")
+		assert.StringContains(t, string(body), "Over the wintry forest")
+		assert.Equal(t, strings.Contains(string(body), "An old silent pond"), false)
+		assert.Equal(t, linkRX("Next &rarr;").Match(body), false)
+
+		body = follow(t, body, "&larr; Previous")
+		assert.StringContains(t, string(body), "An old silent pond")
+		assert.Equal(t, linkRX("&larr; Previous").Match(body), false)
+		assert.Equal(t, linkRX("Next &rarr;").Match(body), true)
+	})
+}
+
+func TestSearch(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Search box",
+			path:     "/search",
+			wantCode: http.StatusOK,
+			wantBody: "<input type='search' name='q' value=''",
+		},
+		{
+			name:     "Highlights matches",
+			path:     "/search?q=Silent",
+			wantCode: http.StatusOK,
+			wantBody: "<pre>An old <mark>silent</mark> pond...</pre>",
+		},
+		{
+			name:     "Prefix",
+			path:     "/search?q=wint*",
+			wantCode: http.StatusOK,
+			wantBody: "<pre>Over the <mark>wint</mark>ry forest, winds howl in rage...</pre>",
+		},
+		{
+			name:     "Keeps the query",
+			path:     "/search?q=silent",
+			wantCode: http.StatusOK,
+			wantBody: "<input type='search' name='q' value='silent'",
+		},
+		{
+			name:     "Private snippets of others",
+			path:     "/search?q=diary",
+			wantCode: http.StatusOK,
+			wantBody: "No snippets match your search.",
+		},
+		{
+			name:     "Password-protected snippets of others",
+			path:     "/search?q=horse",
+			wantCode: http.StatusOK,
+			wantBody: "No snippets match your search.",
+		},
+		{
+			name:     "Query is escaped",
+			path:     "/search?q=%3Cscript%3E",
+			wantCode: http.StatusOK,
+			wantBody: "&ldquo;&lt;script&gt;&rdquo;",
+		},
+		{
+			name:     "Past the last page",
+			path:     "/search?q=pond&page=2",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Query too long",
+			path:     "/search?q=" + strings.Repeat("a", 201),
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.path)
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantBody != "" {
+				assert.StringContains(t, string(body), tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("Own private snippets", func(t *testing.T) {
+		ts.login(t)
+		code, _, body := ts.get(t, "/search?q=diary")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, string(body), "<a href='/s/Zx9Wv8Ut7s'>Dear <mark>diary</mark></a>")
+	})
+}
+
+func TestSnippetViewNumericIDsDisabled(t *testing.T) {
+	app := newTestApplication(t)
+	app.numericIDs = false
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, _ := ts.get(t, "/snippet/view/1")
+	assert.Equal(t, code, http.StatusNotFound)
+	code, _, _ = ts.get(t, "/s/aB3dE5gH7j")
+	assert.Equal(t, code, http.StatusOK)
+}
+
+func TestSnippetViewPrivate(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, _ := ts.get(t, "/snippet/view/4/history")
+	assert.Equal(t, code, http.StatusNotFound)
+
+	ts.login(t)
+	code, _, body := ts.get(t, "/snippet/view/4")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, string(body), "Today I wrote a private snippet")
+	assert.StringContains(t, string(body), "<em>private</em>")
+}
+
 func TestUserSignup(t *testing.T) {
 	app := newTestApplication(t)
 	ts := newTestServer(t, app.routes())
@@ -202,6 +536,187 @@ func TestAccountView(t *testing.T) {
 	code, _, body = ts.get(t, "/account/view")
 	assert.Equal(t, code, http.StatusOK)
 	assert.StringContains(t, string(body), `<h2>Your Account</h2>`)
+	assert.StringContains(t, string(body), `<h2>My Snippets</h2>`)
+	assert.StringContains(t, string(body), `<a href='/s/aB3dE5gH7j'>An old silent pond</a>`)
+}
+
+func TestAccountTokens(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	code, _, body := ts.get(t, "/account/view")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, string(body), "<h2>API Tokens</h2>")
+	assert.StringContains(t, string(body), "<td>laptop</td>")
+	assert.StringContains(t, string(body), "<td>read, write, delete</td>")
+	assert.StringContains(t, string(body), "<td>read</td>")
+	assert.StringContains(t, string(body), "<form action='/account/tokens/delete/1' method='POST'>")
+	assert.StringContains(t, string(body), "<option value='90' selected>")
+
+	form := url.Values{}
+	form.Add("csrf_token", csrfToken)
+	form.Add("name", " ")
+	form.Add("expires_days", "400")
+	code, _, body = ts.postForm(t, "/account/tokens", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, string(body), "This field cannot be blank")
+	assert.StringContains(t, string(body), "Pick at least one scope")
+	assert.StringContains(t, string(body), "This field must be between 0 and 365")
+
+	form.Set("name", "ci")
+	form.Set("read", "true")
+	form.Set("delete", "true")
+	form.Set("expires_days", "0")
+	code, headers, body := ts.postForm(t, "/account/tokens", form)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, headers.Get("Cache-Control"), "no-store")
+	assert.StringContains(t, string(body), "<code>sb_n3wT0k3n</code>")
+	assert.StringContains(t, string(body), "<td>ci</td>\n<td>read, delete</td>")
+
+	form.Del("name")
+	code, headers, _ = ts.postForm(t, "/account/tokens/delete/1", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/account/view")
+	code, _, _ = ts.postForm(t, "/account/tokens/delete/5", form)
+	assert.Equal(t, code, http.StatusNotFound)
+	code, _, _ = ts.postForm(t, "/account/tokens/delete/abc", form)
+	assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	form := url.Values{}
+	form.Add("title", "From a script")
+	form.Add("content", "echo hello")
+	form.Add("expires", "1")
+	form.Add("expires_unit", "days")
+	form.Add("visibility", "public")
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Page",
+			method:   http.MethodGet,
+			path:     "/snippet/create",
+			token:    "sb_valid",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Form without CSRF token",
+			method:   http.MethodPost,
+			path:     "/snippet/create",
+			token:    "sb_valid",
+			body:     form.Encode(),
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Missing scope",
+			method:   http.MethodPost,
+			path:     "/snippet/create",
+			token:    "sb_readonly",
+			body:     form.Encode(),
+			wantCode: http.StatusForbidden,
+			wantBody: "the API token lacks the write scope",
+		},
+		{
+			name:     "Read scope",
+			method:   http.MethodGet,
+			path:     "/s/Zx9Wv8Ut7s",
+			token:    "sb_readonly",
+			wantCode: http.StatusOK,
+			wantBody: "Dear diary",
+		},
+		{
+			name:     "Write scope",
+			method:   http.MethodPost,
+			path:     "/snippet/create",
+			token:    "sb_writeonly",
+			body:     form.Encode(),
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Public snippet without read scope",
+			method:   http.MethodGet,
+			path:     "/s/aB3dE5gH7j",
+			token:    "sb_writeonly",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond...",
+		},
+		{
+			name:     "Private snippet without read scope",
+			method:   http.MethodGet,
+			path:     "/s/Zx9Wv8Ut7s",
+			token:    "sb_writeonly",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Delete scope",
+			method:   http.MethodPost,
+			path:     "/snippet/delete/aB3dE5gH7j",
+			token:    "sb_readonly",
+			wantCode: http.StatusForbidden,
+			wantBody: "the API token lacks the delete scope",
+		},
+		{
+			name:     "Account",
+			method:   http.MethodGet,
+			path:     "/account/view",
+			token:    "sb_valid",
+			wantCode: http.StatusForbidden,
+			wantBody: "API tokens can't be used to manage the account",
+		},
+		{
+			name:     "Invalid token",
+			method:   http.MethodGet,
+			path:     "/snippet/create",
+			token:    "sb_invalid",
+			wantCode: http.StatusUnauthorized,
+			wantBody: "invalid API token",
+		},
+		{
+			name:     "API",
+			method:   http.MethodDelete,
+			path:     "/api/v1/snippets/aB3dE5gH7j",
+			token:    "sb_readonly",
+			wantCode: http.StatusForbidden,
+			wantBody: `{"error":{"status":403,"message":"the API token lacks the delete scope"}}`,
+		},
+		{
+			name:     "Paste",
+			method:   http.MethodPost,
+			path:     "/paste",
+			token:    "sb_readonly",
+			body:     "echo hello",
+			wantCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Authorization": {"Bearer " + tt.token}}
+			if tt.body != "" {
+				header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			code, _, body := ts.request(t, tt.method, tt.path, header, tt.body)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, string(body), tt.wantBody)
+		})
+	}
+
+	// Forms posted from a session still need a CSRF token.
+	ts.login(t)
+	code, _, _ := ts.postForm(t, "/snippet/create", form)
+	assert.Equal(t, code, http.StatusBadRequest)
 }
 
 func TestCreateSnippet(t *testing.T) {
@@ -233,13 +748,20 @@ func TestCreateSnippet(t *testing.T) {
 			validExpires = "365"
 		)
 		tests := []struct {
-			name      string
-			title     string
-			content   string
-			expires   string
-			csrfToken string
-			wantCode  int
-			wantBody  string
+			name       string
+			title      string
+			content    string
+			expires    string
+			unit       string
+			expiresAt  string
+			visibility string
+			password   string
+			encrypted  bool
+			tags       string
+			language   string
+			csrfToken  string
+			wantCode   int
+			wantBody   string
 		}{
 			{
 				name:      "Valid Create Snippet",
@@ -277,23 +799,197 @@ func TestCreateSnippet(t *testing.T) {
 				wantBody:  "This field cannot be blank",
 			},
 			{
-				name:      "Invalid Value",
+				name:      "Too Far Away",
 				title:     validContent,
 				content:   validContent,
-				expires:   "255",
+				expires:   "366",
 				csrfToken: csrfToken,
 				wantCode:  http.StatusUnprocessableEntity,
-				wantBody:  "This field must equal 1, 7, or 365",
+				wantBody:  "This field cannot be more than 365 days away",
 			},
-		}
-		for _, tt := range tests {
-			t.Run(tt.name, func(t *testing.T) {
-				form := url.Values{}
-				form.Add("title", tt.title)
-				form.Add("content", tt.content)
-				form.Add("expires", tt.expires)
-				form.Add("csrf_token", tt.csrfToken)
-				code, _, body := ts.postForm(t, "/snippet/create", form)
+			{
+				name:      "Negative Amount",
+				title:     validTitle,
+				content:   validContent,
+				expires:   "-1",
+				unit:      "hours",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "This field must be a positive number",
+			},
+			{
+				name:      "Minutes",
+				title:     validTitle,
+				content:   validContent,
+				expires:   "90",
+				unit:      "minutes",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusSeeOther,
+			},
+			{
+				name:      "Never",
+				title:     validTitle,
+				content:   validContent,
+				unit:      "never",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusSeeOther,
+			},
+			{
+				name:      "Date",
+				title:     validTitle,
+				content:   validContent,
+				unit:      "date",
+				expiresAt: time.Now().UTC().AddDate(0, 1, 0).Format("2006-01-02T15:04"),
+				csrfToken: csrfToken,
+				wantCode:  http.StatusSeeOther,
+			},
+			{
+				name:      "Date In The Past",
+				title:     validTitle,
+				content:   validContent,
+				unit:      "date",
+				expiresAt: "2020-01-01T10:00",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "This field must be in the future",
+			},
+			{
+				name:      "Invalid Unit",
+				title:     validTitle,
+				content:   validContent,
+				expires:   "1",
+				unit:      "fortnights",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "This field must be in minutes, hours, days, on a date, or never",
+			},
+			{
+				name:       "Unlisted",
+				title:      validTitle,
+				content:    validContent,
+				expires:    validExpires,
+				visibility: "unlisted",
+				csrfToken:  csrfToken,
+				wantCode:   http.StatusSeeOther,
+			},
+			{
+				name:       "Invalid Visibility",
+				title:      validTitle,
+				content:    validContent,
+				expires:    validExpires,
+				visibility: "secret",
+				csrfToken:  csrfToken,
+				wantCode:   http.StatusUnprocessableEntity,
+				wantBody:   "This field must be public, unlisted, or private",
+			},
+			{
+				name:      "Password Protected",
+				title:     validTitle,
+				content:   validContent,
+				expires:   validExpires,
+				password:  "open sesame",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusSeeOther,
+			},
+			{
+				name:      "Short Password",
+				title:     validTitle,
+				content:   validContent,
+				expires:   validExpires,
+				password:  "sesame",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "This field must be at least 8 characters long",
+			},
+			{
+				name:      "Encrypted",
+				title:     validTitle,
+				content:   "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJw",
+				expires:   validExpires,
+				encrypted: true,
+				csrfToken: csrfToken,
+				wantCode:  http.StatusSeeOther,
+			},
+			{
+				name:      "Tags",
+				title:     validTitle,
+				content:   validContent,
+				expires:   validExpires,
+				tags:      "Go, concurrency go",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusSeeOther,
+			},
+			{
+				name:      "Too Many Tags",
+				title:     validTitle,
+				content:   validContent,
+				expires:   validExpires,
+				tags:      "a b c d e f",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "This field cannot have more than 5 tags",
+			},
+			{
+				name:      "Invalid Tag",
+				title:     validTitle,
+				content:   validContent,
+				expires:   validExpires,
+				tags:      "c#",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "Tags can only contain letters, digits, dots and dashes",
+			},
+			{
+				name:      "Encrypted Plaintext",
+				title:     validTitle,
+				content:   validContent,
+				expires:   validExpires,
+				encrypted: true,
+				csrfToken: csrfToken,
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "This field must be encrypted in your browser",
+			},
+			{
+				name:      "Language",
+				title:     validTitle,
+				content:   "package main",
+				expires:   validExpires,
+				language:  "go",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusSeeOther,
+			},
+			{
+				name:      "Unknown Language",
+				title:     validTitle,
+				content:   validContent,
+				expires:   validExpires,
+				language:  "klingon",
+				csrfToken: csrfToken,
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "This field must be one of the listed languages",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				form := url.Values{}
+				form.Add("title", tt.title)
+				form.Add("content", tt.content)
+				form.Add("expires", tt.expires)
+				if tt.unit != "" {
+					form.Add("expires_unit", tt.unit)
+					form.Add("expires_at", tt.expiresAt)
+				}
+				if tt.visibility != "" {
+					form.Add("visibility", tt.visibility)
+				}
+				form.Add("password", tt.password)
+				form.Add("tags", tt.tags)
+				form.Add("language", tt.language)
+				if tt.encrypted {
+					form.Add("encrypted", "true")
+				}
+				form.Add("csrf_token", tt.csrfToken)
+				code, _, body := ts.postForm(t, "/snippet/create", form)
 				assert.Equal(t, code, tt.wantCode)
 
 				if tt.wantBody != "" {
@@ -536,3 +1232,619 @@ func TestPing(t *testing.T) {
 		t.Errorf("want body to equal %q", "OK")
 	}
 }
+
+func TestSnippetEdit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		code, headers, _ := ts.get(t, "/snippet/edit/1")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	csrfToken := ts.login(t)
+
+	t.Run("View form", func(t *testing.T) {
+		code, _, body := ts.get(t, "/snippet/edit/1")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, string(body), `<form action='/snippet/edit/aB3dE5gH7j' method='POST'>`)
+		assert.StringContains(t, string(body), "An old silent pond...")
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		title    string
+		content  string
+		expires  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Valid edit",
+			path:     "/snippet/edit/1",
+			title:    "An old silent pond",
+			content:  "A frog jumps into the pond",
+			expires:  "7",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Blank content",
+			path:     "/snippet/edit/1",
+			title:    "An old silent pond",
+			content:  "",
+			expires:  "7",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be blank",
+		},
+		{
+			name:     "Not the owner",
+			path:     "/snippet/edit/3",
+			title:    "Mine now",
+			content:  "Mine now",
+			expires:  "7",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Non-existent ID",
+			path:     "/snippet/edit/2",
+			title:    "Mine now",
+			content:  "Mine now",
+			expires:  "7",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("content", tt.content)
+			form.Add("expires", tt.expires)
+			form.Add("csrf_token", csrfToken)
+			code, _, body := ts.postForm(t, tt.path, form)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, string(body), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetDelete(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	csrfToken := ts.login(t)
+
+	tests := []struct {
+		name         string
+		path         string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Owner",
+			path:         "/snippet/delete/1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/account/view",
+		},
+		{
+			name:     "Not the owner",
+			path:     "/snippet/delete/3",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Non-existent ID",
+			path:     "/snippet/delete/2",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", csrfToken)
+			code, headers, _ := ts.postForm(t, tt.path, form)
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestSnippetLanguage(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Only the owner can override the language.
+	_, _, body := ts.get(t, "/s/D3t3cT3dGo")
+	assert.Equal(t, strings.Contains(string(body), "/snippet/language/"), false)
+
+	csrfToken := ts.login(t)
+	_, _, body = ts.get(t, "/s/D3t3cT3dGo")
+	assert.StringContains(t, string(body), "<form action='/snippet/language/D3t3cT3dGo' method='POST'>")
+	assert.StringContains(t, string(body), "<option value='' selected>Detect automatically</option>")
+
+	tests := []struct {
+		name         string
+		path         string
+		language     string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Override",
+			path:         "/snippet/language/D3t3cT3dGo",
+			language:     "javascript",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/D3t3cT3dGo",
+		},
+		{
+			name:         "Plain text",
+			path:         "/snippet/language/D3t3cT3dGo",
+			language:     "text",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/D3t3cT3dGo",
+		},
+		{
+			name:         "Detect again",
+			path:         "/snippet/language/D3t3cT3dGo",
+			language:     "",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/D3t3cT3dGo",
+		},
+		{
+			name:     "Unknown language",
+			path:     "/snippet/language/D3t3cT3dGo",
+			language: "klingon",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Not the owner",
+			path:     "/snippet/language/3",
+			language: "go",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Non-existent ID",
+			path:     "/snippet/language/2",
+			language: "go",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("language", tt.language)
+			form.Add("csrf_token", csrfToken)
+			code, headers, _ := ts.postForm(t, tt.path, form)
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestSnippetFormLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           snippetCreateForm
+		wantLanguage   string
+		wantConfidence bool
+	}{
+		{
+			name:         "Chosen",
+			form:         snippetCreateForm{Language: "sql", Content: "package main"},
+			wantLanguage: "sql",
+		},
+		{
+			name:         "Plain text",
+			form:         snippetCreateForm{Language: plainText, Content: "package main"},
+			wantLanguage: "",
+		},
+		{
+			name:           "Detected",
+			form:           snippetCreateForm{Content: "#!/bin/sh\necho hi"},
+			wantLanguage:   "shell",
+			wantConfidence: true,
+		},
+		{
+			name:         "Nothing detected",
+			form:         snippetCreateForm{Content: "An old silent pond..."},
+			wantLanguage: "",
+		},
+		{
+			name:         "Encrypted",
+			form:         snippetCreateForm{Content: "#!/bin/sh\necho hi", Encrypted: true},
+			wantLanguage: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			language, confidence := tt.form.language()
+			assert.Equal(t, language, tt.wantLanguage)
+			assert.Equal(t, confidence > 0, tt.wantConfidence)
+		})
+	}
+}
+
+func TestSnippetRaw(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name             string
+		path             string
+		wantCode         int
+		wantBody         string
+		wantCacheControl string
+		wantDisposition  string
+	}{
+		{
+			name:             "Raw",
+			path:             "/snippet/raw/aB3dE5gH7j",
+			wantCode:         http.StatusOK,
+			wantBody:         "An old silent pond...",
+			wantCacheControl: "public, no-cache",
+		},
+		{
+			name:             "Numeric ID",
+			path:             "/snippet/raw/1",
+			wantCode:         http.StatusOK,
+			wantBody:         "An old silent pond...",
+			wantCacheControl: "public, no-cache",
+		},
+		{
+			name:             "Download",
+			path:             "/snippet/download/Qw3rTy7uIo",
+			wantCode:         http.StatusOK,
+			wantBody:         "Over the wintry forest, winds howl in rage...",
+			wantCacheControl: "public, no-cache",
+			wantDisposition:  `attachment; filename=over-the-wintry-forest.py`,
+		},
+		{
+			name:            "Download plain text",
+			path:            "/snippet/download/aB3dE5gH7j",
+			wantCode:        http.StatusOK,
+			wantDisposition: `attachment; filename=an-old-silent-pond.txt`,
+		},
+		{
+			name:             "Unlisted",
+			path:             "/snippet/raw/D3t3cT3dGo",
+			wantCode:         http.StatusOK,
+			wantBody:         "package main\n\nfunc main() {}",
+			wantCacheControl: "private, no-cache",
+		},
+		{
+			name:     "Private snippet of someone else",
+			path:     "/snippet/raw/Zx9Wv8Ut7s",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Burn after reading",
+			path:     "/snippet/raw/Bu7nAfT3rR",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Password protected",
+			path:     "/snippet/download/Pr0tEcT3dS",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Non-existent slug",
+			path:     "/snippet/raw/aaaaaaaaaa",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.get(t, tt.path)
+			assert.Equal(t, code, tt.wantCode)
+			if code != http.StatusOK {
+				return
+			}
+			assert.Equal(t, headers.Get("Content-Type"), "text/plain; charset=utf-8")
+			assert.Equal(t, headers.Get("Content-Disposition"), tt.wantDisposition)
+			if tt.wantBody != "" {
+				assert.Equal(t, string(body), tt.wantBody)
+			}
+			if tt.wantCacheControl != "" {
+				assert.Equal(t, headers.Get("Cache-Control"), tt.wantCacheControl)
+			}
+		})
+	}
+
+	t.Run("Unlocked", func(t *testing.T) {
+		_, _, body := ts.get(t, "/s/Pr0tEcT3dS")
+		form := url.Values{}
+		form.Add("password", "open sesame")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		ts.postForm(t, "/s/Pr0tEcT3dS/unlock", form)
+
+		code, headers, body := ts.get(t, "/snippet/raw/Pr0tEcT3dS")
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, string(body), "correct horse battery staple")
+		assert.Equal(t, headers.Get("Cache-Control"), "no-store")
+	})
+
+	t.Run("Revalidation", func(t *testing.T) {
+		_, headers, _ := ts.get(t, "/snippet/raw/aB3dE5gH7j")
+		etag := headers.Get("ETag")
+		assert.Equal(t, etag != "", true)
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/snippet/raw/aB3dE5gH7j", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("If-None-Match", etag)
+		rs, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.Body.Close()
+		assert.Equal(t, rs.StatusCode, http.StatusNotModified)
+	})
+}
+
+func TestDownloadName(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet *models.Snippet
+		want    string
+	}{
+		{
+			name:    "Title",
+			snippet: &models.Snippet{Title: "Hello, World!", Language: "go"},
+			want:    "hello-world.go",
+		},
+		{
+			name:    "Unicode",
+			snippet: &models.Snippet{Title: "  Größe übersetzen  "},
+			want:    "größe-übersetzen.txt",
+		},
+		{
+			name:    "No letters",
+			snippet: &models.Snippet{Slug: "aB3dE5gH7j", Title: "!!!", Language: "shell"},
+			want:    "snippet-aB3dE5gH7j.sh",
+		},
+		{
+			name:    "Long title",
+			snippet: &models.Snippet{Title: strings.Repeat("abcdefghi ", 10)},
+			want:    strings.TrimSuffix(strings.Repeat("abcdefghi-", 5), "-") + ".txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, downloadName(tt.snippet), tt.want)
+		})
+	}
+}
+
+func TestSnippetHistory(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, body := ts.get(t, "/snippet/view/1/history")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, string(body), "<td>#2</td>")
+	assert.StringContains(t, string(body), "<td>#1</td>")
+	assert.StringContains(t, string(body), `<form action='/s/aB3dE5gH7j/diff' method='GET'>`)
+
+	code, _, _ = ts.get(t, "/snippet/view/2/history")
+	assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestSnippetDiff(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody []string
+	}{
+		{
+			name:     "Valid revisions",
+			path:     "/snippet/view/1/diff?from=1&to=2",
+			wantCode: http.StatusOK,
+			wantBody: []string{
+				"@@ -1,1 &#43;1,1 @@",
+				"<tr class='diff-delete'>",
+				"<pre>-An old pond...</pre>",
+				"<pre>&#43;An old silent pond...</pre>",
+				"Title changed from <del>An old pond</del> to <ins>An old silent pond</ins>",
+			},
+		},
+		{
+			name:     "Same revision",
+			path:     "/snippet/view/1/diff?from=2&to=2",
+			wantCode: http.StatusOK,
+			wantBody: []string{"The content of these revisions is identical."},
+		},
+		{
+			name:     "Missing revision",
+			path:     "/snippet/view/1/diff?from=1&to=5",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid revision",
+			path:     "/snippet/view/1/diff?from=foo&to=2",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.path)
+			assert.Equal(t, code, tt.wantCode)
+			for _, want := range tt.wantBody {
+				assert.StringContains(t, string(body), want)
+			}
+		})
+	}
+}
+
+func TestSnippetBurnAfterReading(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, headers, body := ts.get(t, "/s/Bu7nAfT3rR")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, headers.Get("Cache-Control"), "no-store")
+	assert.StringContains(t, string(body), "<form action='/s/Bu7nAfT3rR' method='POST'>")
+	if strings.Contains(string(body), "The door code is 1234") {
+		t.Error("interstitial must not reveal the snippet content")
+	}
+	csrfToken := extractCSRFToken(t, body)
+
+	code, _, _ = ts.get(t, "/s/Bu7nAfT3rR/history")
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code, _, _ = ts.postForm(t, "/s/Bu7nAfT3rR", url.Values{})
+	assert.Equal(t, code, http.StatusBadRequest)
+
+	form := url.Values{}
+	form.Add("csrf_token", csrfToken)
+	code, _, body = ts.postForm(t, "/s/Bu7nAfT3rR", form)
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, string(body), "The door code is 1234")
+	assert.StringContains(t, string(body), "This snippet has now been deleted.")
+
+	code, headers, _ = ts.postForm(t, "/s/aB3dE5gH7j", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/s/aB3dE5gH7j")
+}
+
+// Anonymous snippets belong to nobody, so anonymous visitors don't get to
+// read them as their owner would.
+func TestSnippetAnonymous(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, body := ts.get(t, "/s/An0nYm0uSp")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, string(body), "by Anonymous will be deleted as soon as you open it")
+	if strings.Contains(string(body), "Nobody owns this") {
+		t.Error("interstitial must not reveal the snippet content")
+	}
+	code, _, _ = ts.get(t, "/snippet/raw/An0nYm0uSp")
+	assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestSnippetEncrypted(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, headers, body := ts.get(t, "/s/3nCrYpT3dX")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, headers.Get("Content-Security-Policy"), "default-src 'self'")
+	assert.StringContains(t, string(body), "<code data-ciphertext='AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJw'>")
+	// Decryption happens in main.js; inline scripts would be blocked by the CSP.
+	assert.Equal(t, strings.Count(string(body), "<script"), 1)
+
+	csrfToken := ts.login(t)
+	code, _, body = ts.get(t, "/s/3nCrYpT3dX")
+	assert.Equal(t, code, http.StatusOK)
+	if strings.Contains(string(body), "/snippet/edit/3nCrYpT3dX") {
+		t.Error("encrypted snippets must not link to the edit page")
+	}
+
+	code, _, _ = ts.get(t, "/snippet/edit/3nCrYpT3dX")
+	assert.Equal(t, code, http.StatusBadRequest)
+
+	form := url.Values{}
+	form.Add("title", "Launch codes")
+	form.Add("content", "0000")
+	form.Add("expires", "7")
+	form.Add("csrf_token", csrfToken)
+	code, _, _ = ts.postForm(t, "/snippet/edit/3nCrYpT3dX", form)
+	assert.Equal(t, code, http.StatusBadRequest)
+}
+
+func TestSnippetPasswordProtected(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, headers, body := ts.get(t, "/s/Pr0tEcT3dS")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, headers.Get("Cache-Control"), "no-store")
+	assert.StringContains(t, string(body), "<form action='/s/Pr0tEcT3dS/unlock' method='POST' novalidate>")
+	if strings.Contains(string(body), "correct horse battery staple") {
+		t.Error("password prompt must not reveal the snippet content")
+	}
+	csrfToken := extractCSRFToken(t, body)
+
+	code, headers, _ = ts.get(t, "/s/Pr0tEcT3dS/history")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/s/Pr0tEcT3dS")
+
+	tests := []struct {
+		name     string
+		password string
+		wantCode int
+		wantBody string
+	}{
+		{"Blank password", "", http.StatusUnprocessableEntity, "This field cannot be blank"},
+		{"Wrong password", "open says me", http.StatusUnprocessableEntity, "Password is incorrect"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("password", tt.password)
+			form.Add("csrf_token", csrfToken)
+			code, _, body := ts.postForm(t, "/s/Pr0tEcT3dS/unlock", form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, string(body), tt.wantBody)
+		})
+	}
+
+	form := url.Values{}
+	form.Add("password", "open sesame")
+	form.Add("csrf_token", csrfToken)
+	code, headers, _ = ts.postForm(t, "/s/Pr0tEcT3dS/unlock", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/s/Pr0tEcT3dS")
+
+	code, _, body = ts.get(t, "/s/Pr0tEcT3dS")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, string(body), "correct horse battery staple")
+
+	code, _, _ = ts.get(t, "/s/Pr0tEcT3dS/history")
+	assert.Equal(t, code, http.StatusOK)
+}
+
+func TestSnippetUnlockRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/s/Pr0tEcT3dS")
+	csrfToken := extractCSRFToken(t, body)
+
+	form := url.Values{}
+	form.Add("password", "not the password")
+	form.Add("csrf_token", csrfToken)
+	for i := 0; i < 5; i++ {
+		code, _, _ := ts.postForm(t, "/s/Pr0tEcT3dS/unlock", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+	}
+
+	// Once the limit is reached even the right password is refused.
+	form.Set("password", "open sesame")
+	code, _, body := ts.postForm(t, "/s/Pr0tEcT3dS/unlock", form)
+	assert.Equal(t, code, http.StatusTooManyRequests)
+	assert.StringContains(t, string(body), "Too many failed attempts")
+}
@@ -2,23 +2,33 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/go-playground/form/v4"
+	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/nosurf"
+	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/syntax"
 )
 
 func (app *application) newTemplateData(r *http.Request) *templateData {
 
 	return &templateData{
-		CSRFToken:       nosurf.Token(r),
-		IsAuthenticated: app.isAuthenticated(r),
-		CurrentYear:     time.Now().Year(),
-		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
+		CSRFToken:           nosurf.Token(r),
+		IsAuthenticated:     app.isAuthenticated(r),
+		AuthenticatedUserID: app.authenticatedUserID(r),
+		CurrentYear:         time.Now().Year(),
+		Flash:               app.sessionManager.PopString(r.Context(), "flash"),
 	}
 }
 func (app *application) render(w http.ResponseWriter, status int, page string, data *templateData) {
@@ -60,6 +70,131 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 	}
 	return isAuthenticated
 }
+
+// authenticatedUserID returns the ID of the logged in user, or zero when the
+// request is not authenticated.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	if !app.isAuthenticated(r) {
+		return 0
+	}
+	id, _ := r.Context().Value(authenticatedUserIDContextKey).(int)
+	return id
+}
+
+// apiToken returns the API token that authenticated the request, or nil
+// when it was authenticated by a session or not at all.
+func apiToken(r *http.Request) *models.Token {
+	token, _ := r.Context().Value(apiTokenContextKey).(*models.Token)
+	return token
+}
+
+// unauthorized tells the client to authenticate with an API token, in JSON
+// for API requests and in plain text otherwise.
+func (app *application) unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="snippetbox"`)
+	if isAPIRequest(r) {
+		app.apiError(w, http.StatusUnauthorized, message)
+		return
+	}
+	http.Error(w, message, http.StatusUnauthorized)
+}
+
+// forbidden refuses a request that is authenticated but not allowed, in
+// JSON for API requests and in plain text otherwise.
+func (app *application) forbidden(w http.ResponseWriter, r *http.Request, message string) {
+	if isAPIRequest(r) {
+		app.apiError(w, http.StatusForbidden, message)
+		return
+	}
+	http.Error(w, message, http.StatusForbidden)
+}
+
+// lookupSnippet loads the snippet named by the :slug or :id route parameter.
+// The :id parameter accepts a slug as well as a numeric ID, but numeric IDs
+// are only honoured while app.numericIDs is enabled. When the snippet can't
+// be loaded, the appropriate error response has already been written and ok
+// is false.
+func (app *application) lookupSnippet(w http.ResponseWriter, r *http.Request) (snippet *models.Snippet, ok bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+	userID := app.authenticatedUserID(r)
+	ref := params.ByName("slug")
+	if ref == "" {
+		ref = params.ByName("id")
+	}
+	var err error
+	if models.IsNumericID(ref) {
+		id, convErr := strconv.Atoi(ref)
+		if convErr != nil || id < 1 || !app.numericIDs {
+			app.notFound(w)
+			return nil, false
+		}
+		snippet, err = app.snippets.Get(id, userID)
+	} else {
+		snippet, err = app.snippets.GetBySlug(ref, userID)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+			return nil, false
+		}
+		app.serverError(w, err)
+		return nil, false
+	}
+	return snippet, true
+}
+
+// readableSnippet is like lookupSnippet but only succeeds when the current
+// user may see the snippet's content without an extra step. Burn-after-reading
+// snippets can only be read through the confirmation on their view page, so
+// everyone but their owner gets a 404, and locked snippets redirect to the
+// view page where the passphrase can be entered.
+func (app *application) readableSnippet(w http.ResponseWriter, r *http.Request) (snippet *models.Snippet, ok bool) {
+	snippet, ok = app.lookupSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+	if snippet.BurnAfterReading && !snippet.OwnedBy(app.authenticatedUserID(r)) {
+		app.notFound(w)
+		return nil, false
+	}
+	if !app.isUnlocked(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/s/%s", snippet.Slug), http.StatusSeeOther)
+		return nil, false
+	}
+	return snippet, true
+}
+
+// unlockLifetime is how long a successful passphrase entry is remembered.
+const unlockLifetime = time.Hour
+
+func unlockSessionKey(snippetID int) string {
+	return fmt.Sprintf("unlockedSnippet:%d", snippetID)
+}
+
+// isUnlocked reports whether the current user may read a snippet as far as
+// its passphrase is concerned: either it has none, the user owns it, or the
+// passphrase was entered in this session within unlockLifetime.
+func (app *application) isUnlocked(r *http.Request, snippet *models.Snippet) bool {
+	if !snippet.PasswordProtected() || snippet.OwnedBy(app.authenticatedUserID(r)) {
+		return true
+	}
+	until := app.sessionManager.GetInt64(r.Context(), unlockSessionKey(snippet.ID))
+	return time.Now().Unix() < until
+}
+
+// ownedSnippet is like lookupSnippet but also makes sure the snippet belongs
+// to the current user.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (snippet *models.Snippet, ok bool) {
+	snippet, ok = app.lookupSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+	if !snippet.OwnedBy(app.authenticatedUserID(r)) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+	return snippet, true
+}
 func (app *application) decodePostForm(r *http.Request, dst any) error {
 	err := r.ParseForm()
 	if err != nil {
@@ -75,3 +210,139 @@ func (app *application) decodePostForm(r *http.Request, dst any) error {
 	}
 	return nil
 }
+
+// parseTags splits a comma or space separated list of tags, lowercases them
+// and drops duplicates.
+func parseTags(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, tag := range fields {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// readPage returns the 1-based page number from the "page" query parameter
+// of an offset-paginated list. Invalid page numbers get a 404.
+func (app *application) readPage(w http.ResponseWriter, r *http.Request) (int, bool) {
+	s := r.URL.Query().Get("page")
+	if s == "" {
+		return 1, true
+	}
+	page, err := strconv.Atoi(s)
+	if err != nil || page < 1 || page > math.MaxInt32/app.pageSize {
+		app.notFound(w)
+		return 0, false
+	}
+	return page, true
+}
+
+// paginate trims snippets, which should have been fetched with a limit of
+// one more than the page size, to a page and works out the links to the
+// neighbouring pages. Empty pages other than the first get a 404.
+func (app *application) paginate(w http.ResponseWriter, snippets []*models.Snippet, page int) ([]*models.Snippet, *pagination, bool) {
+	if len(snippets) == 0 && page > 1 {
+		app.notFound(w)
+		return nil, nil, false
+	}
+	pages := &pagination{Page: page}
+	if page > 1 {
+		pages.Prev = page - 1
+	}
+	if len(snippets) > app.pageSize {
+		snippets = snippets[:app.pageSize]
+		pages.Next = page + 1
+	}
+	return snippets, pages, true
+}
+
+// formatCursor encodes a position in the snippet archive for use in links,
+// as the Unix time and ID separated by a dot. The database only keeps whole
+// seconds, so nothing is lost.
+func formatCursor(c models.Cursor) string {
+	return fmt.Sprintf("%d.%d", c.Time.Unix(), c.ID)
+}
+
+// parseCursor decodes a cursor made by formatCursor.
+func parseCursor(s string) (models.Cursor, error) {
+	sec, id, ok := strings.Cut(s, ".")
+	if !ok {
+		return models.Cursor{}, fmt.Errorf("invalid cursor %q", s)
+	}
+	unix, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return models.Cursor{}, fmt.Errorf("invalid cursor %q", s)
+	}
+	c := models.Cursor{Time: time.Unix(unix, 0).UTC()}
+	c.ID, err = strconv.Atoi(id)
+	if err != nil || c.ID < 1 {
+		return models.Cursor{}, fmt.Errorf("invalid cursor %q", s)
+	}
+	return c, nil
+}
+
+// serveSnippetContent writes the content of a snippet as plain text. The
+// ETag lets clients revalidate cheaply, and since snippets can be edited,
+// deleted or expire at any time, caches always have to revalidate. Only
+// public snippets may be kept by shared caches, and content unlocked with a
+// password isn't kept at all.
+func serveSnippetContent(w http.ResponseWriter, r *http.Request, snippet *models.Snippet) {
+	sum := sha256.Sum256([]byte(snippet.Content))
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	switch {
+	case snippet.PasswordProtected():
+		w.Header().Set("Cache-Control", "no-store")
+	case snippet.Visibility == models.VisibilityPublic && !snippet.BurnAfterReading:
+		w.Header().Set("Cache-Control", "public, no-cache")
+	default:
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	http.ServeContent(w, r, "", time.Time{}, strings.NewReader(snippet.Content))
+}
+
+// maxFilenameLength is the longest download file name, in characters,
+// before the extension.
+const maxFilenameLength = 50
+
+// downloadName returns a file name for a snippet made from its title, with
+// the extension of its language.
+func downloadName(snippet *models.Snippet) string {
+	// Runs of anything but letters and digits become a single dash.
+	var b strings.Builder
+	n, dash := 0, false
+	for _, r := range strings.ToLower(snippet.Title) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			dash = n > 0
+			continue
+		}
+		if dash {
+			if n+2 > maxFilenameLength {
+				break
+			}
+			b.WriteByte('-')
+			n++
+			dash = false
+		}
+		if n == maxFilenameLength {
+			break
+		}
+		b.WriteRune(r)
+		n++
+	}
+	name := b.String()
+	if name == "" {
+		name = "snippet-" + snippet.Slug
+	}
+	ext := ".txt"
+	if l, ok := syntax.Lookup(snippet.Language); ok {
+		ext = l.Extension
+	}
+	return name + ext
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// schedule runs job in the background every interval until ctx is done. A
+// failing run is logged and doesn't stop the schedule. app.jobs.Wait blocks
+// until every scheduled job has returned.
+func (app *application) schedule(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	app.jobs.Add(1)
+	go func() {
+		defer app.jobs.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := job(ctx); err != nil {
+					app.errorLog.Printf("job %q: %v", name, err)
+				}
+			}
+		}
+	}()
+}
+
+// purgeExpiredSnippets deletes expired snippets batchSize rows at a time, so
+// that a large backlog doesn't hold locks on the table for long, and returns
+// how many were deleted.
+func (app *application) purgeExpiredSnippets(ctx context.Context, batchSize int) (int64, error) {
+	var total int64
+	for ctx.Err() == nil {
+		ids, err := app.snippets.DeleteExpired(batchSize)
+		total += int64(len(ids))
+		if err != nil {
+			return total, err
+		}
+		if len(ids) < batchSize {
+			break
+		}
+	}
+	if total > 0 {
+		app.infoLog.Printf("purged %d expired snippets", total)
+	}
+	return total, nil
+}
+
+// background runs fn once in its own goroutine, for work that the response
+// shouldn't wait for. A failure is logged, and app.jobs.Wait blocks until fn
+// has returned.
+func (app *application) background(name string, fn func() error) {
+	app.jobs.Add(1)
+	go func() {
+		defer app.jobs.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				app.errorLog.Printf("job %q: %v", name, err)
+			}
+		}()
+		if err := fn(); err != nil {
+			app.errorLog.Printf("job %q: %v", name, err)
+		}
+	}()
+}
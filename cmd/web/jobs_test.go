@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/models/mock"
+)
+
+func TestSchedule(t *testing.T) {
+	app := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	runs := make(chan struct{}, 1)
+	app.schedule(ctx, "test", time.Millisecond, func(ctx context.Context) error {
+		select {
+		case runs <- struct{}{}:
+		default:
+		}
+		return errors.New("failing runs are retried")
+	})
+	<-runs
+	<-runs
+	cancel()
+	// Wait must return once the job noticed the cancellation.
+	app.jobs.Wait()
+}
+
+// expiringSnippets pretends to hold a number of expired snippets.
+type expiringSnippets struct {
+	mock.SnippetModel
+	expired int64
+	calls   int
+}
+
+func (m *expiringSnippets) DeleteExpired(limit int) ([]int, error) {
+	m.calls++
+	ids := []int{}
+	for ; m.expired > 0 && len(ids) < limit; m.expired-- {
+		ids = append(ids, int(m.expired))
+	}
+	return ids, nil
+}
+
+func TestPurgeExpiredSnippets(t *testing.T) {
+	tests := []struct {
+		name      string
+		expired   int64
+		batchSize int
+		wantCalls int
+	}{
+		{name: "Nothing expired", expired: 0, batchSize: 10, wantCalls: 1},
+		{name: "Single batch", expired: 7, batchSize: 10, wantCalls: 1},
+		{name: "Several batches", expired: 25, batchSize: 10, wantCalls: 3},
+		{name: "Exact multiple", expired: 20, batchSize: 10, wantCalls: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			snippets := &expiringSnippets{expired: tt.expired}
+			app.snippets = snippets
+
+			n, err := app.purgeExpiredSnippets(context.Background(), tt.batchSize)
+			assert.NilError(t, err)
+			assert.Equal(t, n, tt.expired)
+			assert.Equal(t, snippets.calls, tt.wantCalls)
+		})
+	}
+}
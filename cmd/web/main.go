@@ -1,41 +1,114 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/alexedwards/scs/mysqlstore"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/xyedo/snippetbox/internal/mailer"
 	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/ratelimit"
+	"github.com/xyedo/snippetbox/internal/search"
 )
 
 type application struct {
 	debug          bool
+	numericIDs     bool
+	maxExpiry      time.Duration
+	pageSize       int
 	errorLog       *log.Logger
 	infoLog        *log.Logger
 	sessionManager *scs.SessionManager
 	snippets       models.SnippetModelInterface
 	users          models.UserModelInterface
+	tokens         models.TokenModelInterface
+	passwordResets models.PasswordResetModelInterface
+	webhooks       models.WebhookModelInterface
 
 	templateCache map[string]*template.Template
 	formDecoder   *form.Decoder
+	// unlockLimiter counts failed passphrase attempts per snippet.
+	unlockLimiter *ratelimit.Limiter
+	// pasteLimiter counts anonymous pastes per client address.
+	pasteLimiter *ratelimit.Limiter
+	// resetLimiter counts password reset emails per address.
+	resetLimiter *ratelimit.Limiter
+	// loginLimiter counts failed API logins per client address and email.
+	loginLimiter *ratelimit.Limiter
+	// webhookClient sends webhook deliveries.
+	webhookClient *http.Client
+	mailer        mailer.Mailer
+	// secretKey signs email verification links.
+	secretKey []byte
+	// baseURL is where users reach the site, which links in emails point
+	// to. Unlike the Host header of a request, it can't be forged.
+	baseURL string
+	// jobs tracks the background jobs started with schedule.
+	jobs sync.WaitGroup
 }
 
 func main() {
 	addr := flag.String("addr", ":4000", "HTTP network address")
+	base := flag.String("base-url", "", "scheme and host users reach the site at, which links in emails point to (default https://localhost followed by -addr)")
 	// dsn := flag.String("dsn", "web:pass@/snippetbox?parseTime=true", "mySQL databases")
 	pass := flag.String("passDB", "web:pass@/snippetbox?parseTime=true", "MYSQL DB Password for user:web\n for parsing web:{pass}@/snippetbox?parseTime=true")
 	debug := flag.Bool("debug", false, "debug mode")
+	numericIDs := flag.Bool("numeric-ids", false, "also serve snippets by their sequential numeric ID, for links shared before slugs existed")
+	maxExpiry := flag.Duration("max-expiry", 365*24*time.Hour, "longest time a snippet can be kept, not counting snippets that never expire")
+	pageSize := flag.Int("page-size", 20, "number of snippets per page in listings")
+	purgeInterval := flag.Duration("purge-interval", time.Hour, "how often expired snippets are deleted, 0 to disable")
+	purgeBatch := flag.Int("purge-batch", 1000, "how many expired snippets are deleted per query")
+	pasteLimit := flag.Int("paste-limit", 10, "number of anonymous pastes a client address can make per hour, 0 to require an API token")
+	webhookInterval := flag.Duration("webhook-interval", 10*time.Second, "how often due webhook deliveries are sent, 0 to disable")
+	secret := flag.String("secret", "", "key of at least 32 characters that signs email verification links; a random one is used by default, which breaks the links sent before a restart")
+	mailOutbox := flag.String("mail-outbox", "./tmp/mail", "directory that outgoing emails are written to, one .eml file each")
+	searchBackend := flag.String("search", "index", "search backend: \"index\" for the in-process index built at startup, \"mysql\" for MySQL full-text search")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [purge | backfill-slugs]\n\nThe purge command deletes expired snippets once and exits. The backfill-slugs\ncommand gives a slug to the snippets that have none, see the readme.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+	if *pageSize < 1 {
+		fmt.Fprintln(flag.CommandLine.Output(), "-page-size must be at least 1")
+		os.Exit(2)
+	}
+	if *purgeBatch < 1 {
+		fmt.Fprintln(flag.CommandLine.Output(), "-purge-batch must be at least 1")
+		os.Exit(2)
+	}
+	if *searchBackend != "index" && *searchBackend != "mysql" {
+		fmt.Fprintln(flag.CommandLine.Output(), "-search must be index or mysql")
+		os.Exit(2)
+	}
+	if *base == "" {
+		*base = "https://localhost" + *addr
+	}
+	if u, err := url.Parse(*base); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+		fmt.Fprintln(flag.CommandLine.Output(), "-base-url must be an http or https URL without a path")
+		os.Exit(2)
+	}
+	if *secret != "" && len(*secret) < 32 {
+		fmt.Fprintln(flag.CommandLine.Output(), "-secret must be at least 32 characters long")
+		os.Exit(2)
+	}
 	dsn := fmt.Sprintf("web:%s@/snippetbox?parseTime=true", *pass)
 	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
@@ -45,27 +118,85 @@ func main() {
 	}
 	defer db.Close()
 
+	switch flag.Arg(0) {
+	case "":
+	case "purge":
+		app := &application{
+			infoLog:  infoLog,
+			errorLog: errorLog,
+			snippets: &models.SnippetModel{DB: db},
+		}
+		n, err := app.purgeExpiredSnippets(context.Background(), *purgeBatch)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		infoLog.Printf("purge finished, %d expired snippets deleted", n)
+		return
+	case "backfill-slugs":
+		snippets := &models.SnippetModel{DB: db}
+		n, err := snippets.BackfillSlugs()
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		infoLog.Printf("backfill finished, %d snippets given a slug", n)
+		return
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	templateCache, err := newTemplateCache()
 	if err != nil {
 		errorLog.Fatal(err)
 	}
+	var snippets models.SnippetModelInterface = &models.SnippetModel{DB: db}
+	if *searchBackend == "index" {
+		indexed := &search.Snippets{SnippetModelInterface: snippets, Index: search.NewMemory()}
+		n, err := indexed.Rebuild()
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		infoLog.Printf("indexed %d snippets", n)
+		snippets = indexed
+	}
+	secretKey := []byte(*secret)
+	if len(secretKey) == 0 {
+		secretKey = make([]byte, 32)
+		if _, err = rand.Read(secretKey); err != nil {
+			errorLog.Fatal(err)
+		}
+		infoLog.Print("no -secret given, email verification links will stop working when the server restarts")
+	}
+	infoLog.Printf("writing outgoing emails to %s", *mailOutbox)
 	formDecoder := form.NewDecoder()
 	sessionManager := scs.New()
 	sessionManager.Store = mysqlstore.New(db)
 	sessionManager.Lifetime = 12 * time.Hour
 	app := &application{
 		debug:          *debug,
+		numericIDs:     *numericIDs,
+		maxExpiry:      *maxExpiry,
+		pageSize:       *pageSize,
 		infoLog:        infoLog,
 		errorLog:       errorLog,
 		sessionManager: sessionManager,
-		snippets: &models.SnippetModel{
-			DB: db,
-		},
+		snippets:       snippets,
 		users: &models.UserModel{
 			DB: db,
 		},
-		templateCache: templateCache,
-		formDecoder:   formDecoder,
+		tokens:         &models.TokenModel{DB: db},
+		passwordResets: &models.PasswordResetModel{DB: db},
+		webhooks:       &models.WebhookModel{DB: db},
+		webhookClient:  newWebhookClient(dialPublicOnly),
+		mailer:         &mailer.Outbox{Dir: *mailOutbox},
+		secretKey:      secretKey,
+		baseURL:        strings.TrimSuffix(*base, "/"),
+		templateCache:  templateCache,
+		formDecoder:    formDecoder,
+		unlockLimiter:  ratelimit.New(5, 15*time.Minute),
+		pasteLimiter:   ratelimit.New(*pasteLimit, time.Hour),
+		resetLimiter:   ratelimit.New(3, time.Hour),
+		loginLimiter:   ratelimit.New(5, 15*time.Minute),
 	}
 	tlsConfig := &tls.Config{
 		PreferServerCipherSuites: true,
@@ -81,11 +212,38 @@ func main() {
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *purgeInterval > 0 {
+		app.schedule(ctx, "purge expired snippets", *purgeInterval, func(ctx context.Context) error {
+			_, err := app.purgeExpiredSnippets(ctx, *purgeBatch)
+			return err
+		})
+	}
+	if *webhookInterval > 0 {
+		app.schedule(ctx, "deliver webhooks", *webhookInterval, app.deliverWebhooks)
+	}
+
+	shutdownErr := make(chan error)
+	go func() {
+		<-ctx.Done()
+		infoLog.Print("shutting down server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(shutdownCtx)
+	}()
+
 	infoLog.Printf("starting server on %s\n", *addr)
 	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	if err != nil {
+	if !errors.Is(err, http.ErrServerClosed) {
+		errorLog.Fatal(err)
+	}
+	if err = <-shutdownErr; err != nil {
 		errorLog.Fatal(err)
 	}
+	app.jobs.Wait()
+	infoLog.Print("stopped server")
 }
 func openDB(dsn string) (db *sql.DB, err error) {
 	db, err = sql.Open("mysql", dsn)
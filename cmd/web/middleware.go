@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/justinas/nosurf"
+	"github.com/xyedo/snippetbox/internal/models"
 )
 
 func secureHeaders(next http.Handler) http.Handler {
@@ -22,12 +25,27 @@ func secureHeaders(next http.Handler) http.Handler {
 	})
 }
 
+// secretPaths lists the routes whose last path segment is a secret token,
+// which mustn't end up in the logs.
+var secretPaths = []string{"/user/password/reset/", "/user/verify/"}
+
+// loggedURL returns the URL of a request as it is logged, with the tokens of
+// secretPaths left out.
+func loggedURL(r *http.Request) string {
+	for _, prefix := range secretPaths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return prefix + "REDACTED"
+		}
+	}
+	return r.URL.String()
+}
+
 func (app *application) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		lrw := NewLoggingResponseWriter(w)
 		next.ServeHTTP(lrw, r)
-		app.infoLog.Printf("%s %d %s %v - %s", r.Method, lrw.statusCode, r.URL, time.Since(start), w.Header().Get("Content-Length"))
+		app.infoLog.Printf("%s %d %s %v - %s", r.Method, lrw.statusCode, loggedURL(r), time.Since(start), w.Header().Get("Content-Length"))
 	})
 }
 func (app *application) recoverPanic(next http.Handler) http.Handler {
@@ -54,7 +72,12 @@ func (app *application) requireAuth(next http.Handler) http.Handler {
 }
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
+		// Requests with an API token were authenticated by authenticateToken
+		// already.
+		if apiToken(r) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
 		id := app.sessionManager.GetInt(r.Context(), "authenticateUserID")
 		if id == 0 {
 			next.ServeHTTP(w, r)
@@ -72,14 +95,104 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		// create a new copy of the request (with an isAuthenticatedContextKey
 		// value of true in the request context) and assign it to r.
 		if exists {
-			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
-			r = r.WithContext(ctx)
+			r = r.WithContext(withAuthenticatedUser(r.Context(), id))
 		}
 		// Call the next handler in the chain.
 		next.ServeHTTP(w, r)
 	})
 }
 
+func withAuthenticatedUser(ctx context.Context, userID int) context.Context {
+	ctx = context.WithValue(ctx, isAuthenticatedContextKey, true)
+	return context.WithValue(ctx, authenticatedUserIDContextKey, userID)
+}
+
+// authenticateToken authenticates requests that carry an API token in their
+// Authorization header. It sets up the request context like authenticate
+// does for sessions and records the token, which requireScope checks and
+// which exempts the request from CSRF checks: browsers never attach the
+// header on their own, so a request that has it can't be forged. Requests
+// with an Authorization header that doesn't hold a valid token get a 401.
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		plaintext, ok := bearerToken(header)
+		if !ok {
+			app.unauthorized(w, r, "invalid API token")
+			return
+		}
+		token, err := app.tokens.Authenticate(plaintext)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCredentials) {
+				app.unauthorized(w, r, "invalid API token")
+				return
+			}
+			if isAPIRequest(r) {
+				app.apiServerError(w, err)
+				return
+			}
+			app.serverError(w, err)
+			return
+		}
+		ctx := withAuthenticatedUser(r.Context(), token.UserID)
+		ctx = context.WithValue(ctx, apiTokenContextKey, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireScope refuses requests authenticated by an API token that wasn't
+// granted scope. Other requests are let through.
+func (app *application) requireScope(scope models.Scope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := apiToken(r); token != nil && !token.HasScope(scope) {
+			app.forbidden(w, r, fmt.Sprintf("the API token lacks the %s scope", scope))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readAsVisitor lets requests authenticated by an API token without the read
+// scope through as if they weren't authenticated at all, so that the token
+// can't be used to see its user's private snippets but can still see
+// whatever anyone can.
+func (app *application) readAsVisitor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := apiToken(r); token != nil && !token.HasScope(models.ScopeRead) {
+			r = r.WithContext(context.WithValue(r.Context(), isAuthenticatedContextKey, false))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireSession refuses requests authenticated by an API token, so that a
+// leaked token can't be used to change the password or mint more tokens.
+func (app *application) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiToken(r) != nil {
+			app.forbidden(w, r, "API tokens can't be used to manage the account")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiRequireAuth is requireAuth for the API, which answers with a 401
+// instead of redirecting to the login page.
+func (app *application) apiRequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.unauthorized(w, r, "a valid API token is required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 type loggingResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -92,6 +205,9 @@ func NewLoggingResponseWriter(w http.ResponseWriter) *loggingResponseWriter {
 }
 func NoSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
+	csrfHandler.ExemptFunc(func(r *http.Request) bool {
+		return apiToken(r) != nil
+	})
 	csrfHandler.SetBaseCookie(http.Cookie{
 		HttpOnly: true,
 		Path:     "/",
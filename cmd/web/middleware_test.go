@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/xyedo/snippetbox/internal/assert"
@@ -51,3 +54,33 @@ func TestSecureHeaders(t *testing.T) {
 	// and the response status code and body are as expected.
 	assert.Equal(t, rs.StatusCode, http.StatusOK)
 }
+
+func TestLogRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		urlPath string
+		want    string
+	}{
+		{"Plain path", "/snippet/view/abc?page=2", "GET 200 /snippet/view/abc?page=2 "},
+		{"Reset token", "/user/password/reset/r3s3tT0k3n", "GET 200 /user/password/reset/REDACTED "},
+		{"Verify token", "/user/verify/v3r1fyT0k3n?x=1", "GET 200 /user/verify/REDACTED "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			app := &application{infoLog: log.New(&buf, "", 0)}
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("OK"))
+			})
+
+			r := httptest.NewRequest(http.MethodGet, tt.urlPath, nil)
+			app.logRequest(next).ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.StringContains(t, buf.String(), tt.want)
+			if strings.Contains(buf.String(), "T0k3n") {
+				t.Errorf("log line %q contains the token", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/models"
+)
+
+// The API routes are registered from apiOperations, with the middleware
+// their Auth and Scope call for, and the OpenAPI document of the API is
+// generated from the same table and from the Go types the handlers read and
+// write, so it can't drift from what the API actually does.
+// TestOpenAPIRoutes fails when an API route is registered in routes.go
+// directly instead.
+
+// apiAuth says whether an API operation needs an API token.
+type apiAuth int
+
+const (
+	authNone apiAuth = iota
+	authOptional
+	authRequired
+)
+
+type apiParam struct {
+	Name        string
+	Description string
+}
+
+// apiOperation describes an API route. Request and Response are values of
+// the types of the request and response bodies: a string stands for a plain
+// text body and nil for none. Path parameters are taken from Path.
+type apiOperation struct {
+	ID      string
+	Method  string
+	Path    string
+	Handler func(*application, http.ResponseWriter, *http.Request)
+	Summary string
+	Auth    apiAuth
+	// Scope is the scope tokens need for the operation.
+	Scope models.Scope
+	// Verified says whether the user must have verified their email
+	// address.
+	Verified bool
+	Query    []apiParam
+	Request  any
+	Status   int
+	Response any
+	// Errors lists the error statuses of the operation other than those
+	// that follow from Auth and Scope, and 500.
+	Errors []int
+	// ErrorBody is a value of the type of error bodies, like Response. nil
+	// stands for apiErrorEnvelope, which the routes under /api answer with.
+	ErrorBody any
+}
+
+// apiOperations returns the operations of the API. It is a function rather
+// than a variable since the handlers refer back to it.
+func apiOperations() []apiOperation {
+	return []apiOperation{
+		{
+			ID:       "getOpenAPI",
+			Method:   http.MethodGet,
+			Path:     "/api/openapi.json",
+			Handler:  (*application).openAPI,
+			Summary:  "Get this document",
+			Status:   http.StatusOK,
+			Response: map[string]any{},
+		},
+		{
+			ID:       "paste",
+			Method:   http.MethodPost,
+			Path:     "/paste",
+			Handler:  (*application).paste,
+			Summary:  "Create a snippet from the request body and reply with its link. Without a token the snippet is anonymous and the request rate limited; with one, its user must have verified their email address.",
+			Auth:     authOptional,
+			Scope:    models.ScopeWrite,
+			Verified: true,
+			Query: []apiParam{
+				{"title", "Defaults to Untitled."},
+				{"language", "Guessed from the content by default."},
+				{"expires", "A number followed by m, h or d, or never. Defaults to 7d."},
+				{"visibility", "public, unlisted or private. Defaults to unlisted."},
+			},
+			Request:   "",
+			Status:    http.StatusCreated,
+			Response:  "",
+			Errors:    []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests},
+			ErrorBody: "",
+		},
+		{
+			ID:       "createToken",
+			Method:   http.MethodPost,
+			Path:     "/api/v1/tokens",
+			Handler:  (*application).apiLogin,
+			Summary:  "Exchange an email and password for an API token",
+			Request:  apiLoginRequest{},
+			Status:   http.StatusCreated,
+			Response: apiTokenResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusTooManyRequests},
+		},
+		{
+			ID:       "getCurrentUser",
+			Method:   http.MethodGet,
+			Path:     "/api/v1/user",
+			Handler:  (*application).apiCurrentUser,
+			Summary:  "Get the user of the token",
+			Auth:     authRequired,
+			Scope:    models.ScopeRead,
+			Status:   http.StatusOK,
+			Response: apiUserResponse{},
+		},
+		{
+			ID:       "listUserSnippets",
+			Method:   http.MethodGet,
+			Path:     "/api/v1/user/snippets",
+			Handler:  (*application).apiUserSnippets,
+			Summary:  "List the snippets of the token's user, newest first",
+			Auth:     authRequired,
+			Scope:    models.ScopeRead,
+			Status:   http.StatusOK,
+			Response: apiSnippetsResponse{},
+		},
+		{
+			ID:      "listSnippets",
+			Method:  http.MethodGet,
+			Path:    "/api/v1/snippets",
+			Handler: (*application).apiSnippetList,
+			Summary: "Page through the archive of public snippets",
+			Auth:    authOptional,
+			Scope:   models.ScopeRead,
+			Query: []apiParam{
+				{"sort", "newest, oldest or expiring. Defaults to newest."},
+				{"size", fmt.Sprintf("The number of snippets per page, at most %d.", maxPageSize)},
+				{"after", "The next cursor of the previous page."},
+				{"before", "The prev cursor of the next page."},
+			},
+			Status:   http.StatusOK,
+			Response: apiSnippetsResponse{},
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			ID:       "createSnippet",
+			Method:   http.MethodPost,
+			Path:     "/api/v1/snippets",
+			Handler:  (*application).apiSnippetCreate,
+			Summary:  "Create a snippet. The token's user must have verified their email address.",
+			Auth:     authRequired,
+			Scope:    models.ScopeWrite,
+			Verified: true,
+			Request:  apiSnippetInput{},
+			Status:   http.StatusCreated,
+			Response: apiSnippetResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity},
+		},
+		{
+			ID:       "getSnippet",
+			Method:   http.MethodGet,
+			Path:     "/api/v1/snippets/:slug",
+			Handler:  (*application).apiSnippetGet,
+			Summary:  "Get a snippet with its content",
+			Auth:     authOptional,
+			Scope:    models.ScopeRead,
+			Status:   http.StatusOK,
+			Response: apiSnippetResponse{},
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			ID:       "updateSnippet",
+			Method:   http.MethodPatch,
+			Path:     "/api/v1/snippets/:slug",
+			Handler:  (*application).apiSnippetUpdate,
+			Summary:  "Change the given fields of a snippet of the token's user",
+			Auth:     authRequired,
+			Scope:    models.ScopeWrite,
+			Request:  apiSnippetInput{},
+			Status:   http.StatusOK,
+			Response: apiSnippetResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity},
+		},
+		{
+			ID:      "deleteSnippet",
+			Method:  http.MethodDelete,
+			Path:    "/api/v1/snippets/:slug",
+			Handler: (*application).apiSnippetDelete,
+			Summary: "Delete a snippet of the token's user",
+			Auth:    authRequired,
+			Scope:   models.ScopeDelete,
+			Status:  http.StatusNoContent,
+			Errors:  []int{http.StatusNotFound},
+		},
+	}
+}
+
+// openAPI serves the OpenAPI document of the API.
+func (app *application) openAPI(w http.ResponseWriter, r *http.Request) {
+	app.writeJSON(w, http.StatusOK, openAPIDocument(app.baseURL))
+}
+
+// openAPIDocument returns the OpenAPI document of the API served at
+// serverURL.
+func openAPIDocument(serverURL string) map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]any{}
+	for _, op := range apiOperations() {
+		path := openAPIPath(op.Path)
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.Method)] = op.document(schemas)
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Snippetbox API",
+			"version": "1",
+		},
+		"servers": []any{map[string]any{"url": serverURL}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+var pathParamRX = regexp.MustCompile(`:(\w+)`)
+
+// openAPIPath turns an httprouter path into an OpenAPI one.
+func openAPIPath(path string) string {
+	return pathParamRX.ReplaceAllString(path, "{$1}")
+}
+
+func (op apiOperation) document(schemas map[string]any) map[string]any {
+	d := map[string]any{
+		"operationId": op.ID,
+		"summary":     op.Summary,
+	}
+	if op.Scope != "" {
+		d["description"] = fmt.Sprintf("API tokens need the %s scope.", op.Scope)
+	}
+	switch op.Auth {
+	case authOptional:
+		d["security"] = []any{map[string]any{}, map[string]any{"bearerAuth": []any{}}}
+	case authRequired:
+		d["security"] = []any{map[string]any{"bearerAuth": []any{}}}
+	}
+
+	params := []any{}
+	for _, m := range pathParamRX.FindAllStringSubmatch(op.Path, -1) {
+		params = append(params, map[string]any{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	for _, p := range op.Query {
+		params = append(params, map[string]any{
+			"name":        p.Name,
+			"in":          "query",
+			"description": p.Description,
+			"schema":      map[string]any{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		d["parameters"] = params
+	}
+	if op.Request != nil {
+		d["requestBody"] = map[string]any{
+			"required": true,
+			"content":  openAPIContent(op.Request, schemas),
+		}
+	}
+
+	responses := map[string]any{}
+	success := map[string]any{"description": http.StatusText(op.Status)}
+	if op.Response != nil {
+		success["content"] = openAPIContent(op.Response, schemas)
+	}
+	responses[strconv.Itoa(op.Status)] = success
+	statuses := append([]int{http.StatusInternalServerError}, op.Errors...)
+	if op.Auth != authNone {
+		statuses = append(statuses, http.StatusUnauthorized)
+	}
+	if op.Scope != "" {
+		statuses = append(statuses, http.StatusForbidden)
+	}
+	var errorBody any = apiErrorEnvelope{}
+	if op.ErrorBody != nil {
+		errorBody = op.ErrorBody
+	}
+	for _, status := range statuses {
+		responses[strconv.Itoa(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content":     openAPIContent(errorBody, schemas),
+		}
+	}
+	d["responses"] = responses
+	return d
+}
+
+// openAPIContent describes a request or response body holding values like v.
+func openAPIContent(v any, schemas map[string]any) map[string]any {
+	contentType := "application/json"
+	if _, ok := v.(string); ok {
+		contentType = "text/plain"
+	}
+	return map[string]any{
+		contentType: map[string]any{"schema": jsonSchema(reflect.TypeOf(v), schemas)},
+	}
+}
+
+// enumTypes lists the values of string types that only take a few.
+var enumTypes = map[reflect.Type][]string{
+	reflect.TypeOf(models.Scope("")): {
+		string(models.ScopeRead),
+		string(models.ScopeWrite),
+		string(models.ScopeDelete),
+	},
+	reflect.TypeOf(models.Visibility("")): {
+		string(models.VisibilityPublic),
+		string(models.VisibilityUnlisted),
+		string(models.VisibilityPrivate),
+	},
+}
+
+// jsonSchema returns the schema of values of type t as encoding/json
+// encodes them. Named structs are added to schemas, under their name without
+// the api prefix, and referred to.
+func jsonSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	if values, ok := enumTypes[t]; ok {
+		return map[string]any{"type": "string", "enum": values}
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := jsonSchema(t.Elem(), schemas)
+		if _, ok := s["$ref"]; ok {
+			return map[string]any{"allOf": []any{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+		name := strings.TrimPrefix(t.Name(), "api")
+		if _, ok := schemas[name]; !ok {
+			// Claim the name first in case the struct refers to itself.
+			schemas[name] = nil
+			schemas[name] = structSchema(t, schemas)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		// Interfaces can hold anything.
+		return map[string]any{}
+	}
+}
+
+// structSchema describes the JSON object of a struct. Fields without
+// omitempty are always present and therefore required.
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = jsonSchema(f.Type, schemas)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/models/mock"
+)
+
+// routesFromSource returns the method and path of every route registered in
+// routes.go with a literal path, leaving out those registered from
+// apiOperations.
+func routesFromSource(t *testing.T) [][2]string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "routes.go", nil, 0)
+	assert.NilError(t, err)
+	var routes [][2]string
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+		fun, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || fun.Sel.Name != "Handler" {
+			return true
+		}
+		if recv, ok := fun.X.(*ast.Ident); !ok || recv.Name != "router" {
+			return true
+		}
+		method, ok := call.Args[0].(*ast.SelectorExpr)
+		if ok && method.Sel.Name == "Method" {
+			// router.Handler(op.Method, op.Path, ...) in the loop over
+			// apiOperations.
+			return true
+		}
+		if !ok || !strings.HasPrefix(method.Sel.Name, "Method") {
+			t.Fatalf("route at offset %d: the method must be one of the http.Method constants", call.Pos())
+		}
+		lit, ok := call.Args[1].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			t.Fatalf("route at offset %d: the path must be a string literal", call.Pos())
+		}
+		path, err := strconv.Unquote(lit.Value)
+		assert.NilError(t, err)
+		routes = append(routes, [2]string{strings.ToUpper(strings.TrimPrefix(method.Sel.Name, "Method")), path})
+		return true
+	})
+	return routes
+}
+
+// TestOpenAPIRoutes makes sure that the API routes, those under /api and
+// /paste, are all registered from apiOperations, so that the OpenAPI
+// document describes every one of them.
+func TestOpenAPIRoutes(t *testing.T) {
+	for _, route := range routesFromSource(t) {
+		method, path := route[0], route[1]
+		if strings.HasPrefix(path, "/api/") || path == "/paste" {
+			t.Errorf("%s %s is registered in routes.go rather than from apiOperations", method, path)
+		}
+	}
+
+	paths := openAPIDocument("https://example.com")["paths"].(map[string]any)
+	ids := map[string]bool{}
+	for _, op := range apiOperations() {
+		if ids[op.ID] {
+			t.Errorf("%s is the ID of more than one operation", op.ID)
+		}
+		ids[op.ID] = true
+		if op.Handler == nil {
+			t.Errorf("%s has no handler", op.ID)
+		}
+		if !strings.HasPrefix(op.Path, "/api/") && op.Path != "/paste" {
+			t.Errorf("%s %s is not an API path", op.Method, op.Path)
+		}
+		item, _ := paths[openAPIPath(op.Path)].(map[string]any)
+		if _, ok := item[strings.ToLower(op.Method)]; !ok {
+			t.Errorf("%s %s is missing from the OpenAPI document", op.Method, op.Path)
+		}
+	}
+}
+
+// scopedTokens authenticates "sb_" followed by a comma-separated list of
+// scopes, possibly empty, as a token of the mock user with those scopes.
+type scopedTokens struct {
+	mock.TokenModel
+}
+
+func (m *scopedTokens) Authenticate(token string) (*models.Token, error) {
+	if !strings.HasPrefix(token, "sb_") || token == "sb_bogus" {
+		return nil, models.ErrInvalidCredentials
+	}
+	scopes := []models.Scope{}
+	for _, scope := range strings.Split(strings.TrimPrefix(token, "sb_"), ",") {
+		if scope != "" {
+			scopes = append(scopes, models.Scope(scope))
+		}
+	}
+	return &models.Token{ID: 1, UserID: 1, Scopes: scopes}, nil
+}
+
+// TestOpenAPISecurity sends every operation through the router to make sure
+// that the middleware apiRoute picks enforces Auth and Scope, and that its
+// 401 and 403 answers have the documented content type.
+func TestOpenAPISecurity(t *testing.T) {
+	app := newTestApplication(t)
+	app.tokens = &scopedTokens{}
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	send := func(op apiOperation, token string) (int, string) {
+		header := http.Header{}
+		if token != "" {
+			header.Set("Authorization", "Bearer "+token)
+		}
+		path := pathParamRX.ReplaceAllString(op.Path, "aB3dE5gH7j")
+		code, headers, _ := ts.request(t, op.Method, path, header, "")
+		return code, headers.Get("Content-Type")
+	}
+	for _, op := range apiOperations() {
+		t.Run(op.ID, func(t *testing.T) {
+			wantType := "application/json"
+			if _, ok := op.ErrorBody.(string); ok {
+				wantType = "text/plain"
+			}
+
+			code, _ := send(op, "")
+			assert.Equal(t, code == http.StatusUnauthorized, op.Auth == authRequired)
+			// Only operations that take tokens turn away invalid ones.
+			code, contentType := send(op, "sb_bogus")
+			assert.Equal(t, code == http.StatusUnauthorized, op.Auth != authNone)
+			if code == http.StatusUnauthorized {
+				assert.StringContains(t, contentType, wantType)
+			}
+
+			code, contentType = send(op, "sb_")
+			assert.Equal(t, code == http.StatusForbidden, op.Scope != "")
+			if code == http.StatusForbidden {
+				assert.StringContains(t, contentType, wantType)
+			}
+			// The documented scope is enough on its own.
+			code, _ = send(op, "sb_"+string(op.Scope))
+			assert.Equal(t, code != http.StatusUnauthorized && code != http.StatusForbidden, true)
+		})
+	}
+}
+
+var refRX = regexp.MustCompile(`"\$ref":"#/components/schemas/(\w+)"`)
+
+func TestOpenAPI(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, headers, body := ts.get(t, "/api/openapi.json")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, headers.Get("Content-Type"), "application/json")
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Type     string   `json:"type"`
+					Format   string   `json:"format"`
+					Nullable bool     `json:"nullable"`
+					Enum     []string `json:"enum"`
+				} `json:"properties"`
+				Required []string `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.NilError(t, json.Unmarshal(body, &doc))
+	assert.Equal(t, doc.OpenAPI, "3.0.3")
+	assert.Equal(t, doc.Servers[0].URL, "https://snippetbox.example")
+
+	snippet := doc.Components.Schemas["Snippet"]
+	assert.Equal(t, snippet.Properties["slug"].Type, "string")
+	assert.Equal(t, snippet.Properties["expires"].Format, "date-time")
+	assert.Equal(t, snippet.Properties["expires"].Nullable, true)
+	assert.Equal(t, strings.Join(snippet.Properties["visibility"].Enum, ","), "public,unlisted,private")
+	assert.StringContains(t, strings.Join(snippet.Required, ","), "slug,url,title")
+	assert.Equal(t, len(doc.Components.Schemas["SnippetInput"].Required), 0)
+	assert.Equal(t, strings.Join(doc.Components.Schemas["LoginRequest"].Required, ","), "email,password")
+
+	// Every reference must lead to a schema.
+	for _, ref := range refRX.FindAllStringSubmatch(string(body), -1) {
+		if _, ok := doc.Components.Schemas[ref[1]]; !ok {
+			t.Errorf("dangling reference to %s", ref[1])
+		}
+	}
+	assert.StringContains(t, string(doc.Paths["/api/v1/snippets/{slug}"]["delete"]), `"description":"API tokens need the delete scope."`)
+	assert.StringContains(t, string(doc.Paths["/api/v1/snippets/{slug}"]["delete"]), `"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/ErrorEnvelope"}}}`)
+
+	// Pastes answer errors in plain text, as documented.
+	assert.StringContains(t, string(doc.Paths["/paste"]["post"]), `"400":{"content":{"text/plain":{"schema":{"type":"string"}}}`)
+	code, headers, _ = ts.post(t, "/paste", nil, "")
+	assert.Equal(t, code, http.StatusBadRequest)
+	assert.StringContains(t, headers.Get("Content-Type"), "text/plain")
+}
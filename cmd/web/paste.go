@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xyedo/snippetbox/internal/models"
+)
+
+// shortExpiryUnits maps the unit suffixes of short expiries such as "7d" to
+// the units of the snippet forms.
+var shortExpiryUnits = map[string]string{
+	"m": "minutes",
+	"h": "hours",
+	"d": "days",
+}
+
+var shortExpiryRX = regexp.MustCompile(`^([0-9]{1,9})([mhd])$`)
+
+// parseExpiry sets the expiry of a form from a number followed by m, h or d,
+// or "never", which is how /paste and the API take it.
+func (form *snippetCreateForm) parseExpiry(s string) {
+	switch m := shortExpiryRX.FindStringSubmatch(s); {
+	case s == "never":
+		form.ExpiresUnit = "never"
+	case m != nil:
+		form.Expires, _ = strconv.Atoi(m[1])
+		form.ExpiresUnit = shortExpiryUnits[m[2]]
+	default:
+		form.AddFieldError("expires", "This field must be a number followed by m, h or d, or never")
+	}
+}
+
+// pasteForm fills a snippet form from the query parameters of a paste, so
+// that pastes are validated like snippets created in the browser. They
+// expire after a week unless the expires parameter says otherwise.
+func pasteForm(query url.Values, content string) snippetCreateForm {
+	form := snippetCreateForm{
+		Title:       query.Get("title"),
+		Content:     content,
+		Language:    query.Get("language"),
+		Visibility:  models.Visibility(query.Get("visibility")),
+		Expires:     7,
+		ExpiresUnit: "days",
+	}
+	if form.Title == "" {
+		form.Title = "Untitled"
+	}
+	if form.Visibility == "" {
+		form.Visibility = models.VisibilityUnlisted
+	}
+	if expires := query.Get("expires"); expires != "" {
+		form.parseExpiry(expires)
+	}
+	return form
+}
+
+// paste creates a snippet from the raw request body, for uploads from the
+// command line such as
+//
+//	cat main.go | curl --data-binary @- https://host/paste?language=go
+//
+// The title, language, visibility and expiry come from query parameters and
+// the reply is the snippet's URL. Requests authenticated by an API token
+// with the write scope create snippets owned by the token's user; the others
+// create anonymous snippets and are rate limited per client address. The
+// endpoint has no session and therefore needs no CSRF token.
+func (app *application) paste(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	if userID == 0 && !app.pasteLimiter.Allow(clientIP(r)) {
+		http.Error(w, "too many anonymous pastes, try again later or use an API token", http.StatusTooManyRequests)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxContentSize+1))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxContentSize {
+		http.Error(w, fmt.Sprintf("content cannot be more than %d bytes long", maxContentSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	now := time.Now().UTC()
+	form := pasteForm(r.URL.Query(), string(body))
+	form.validate(now, app.maxExpiry)
+	form.CheckField(utf8.Valid(body), "content", "This field must be UTF-8 text")
+	// Nobody could read a private snippet without an owner.
+	if userID == 0 {
+		form.CheckField(form.Visibility != models.VisibilityPrivate, "visibility", "Anonymous pastes cannot be private")
+	}
+	if !form.Valid() {
+		fields := make([]string, 0, len(form.FieldErrors))
+		for field := range form.FieldErrors {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for i, field := range fields {
+			fields[i] = fmt.Sprintf("%s: %s", field, form.FieldErrors[field])
+		}
+		http.Error(w, strings.Join(fields, "\n"), http.StatusBadRequest)
+		return
+	}
+
+	snippet := &models.Snippet{
+		UserID:     userID,
+		Title:      form.Title,
+		Content:    form.Content,
+		Visibility: form.Visibility,
+		Expires:    form.expiresAt(now),
+	}
+	snippet.Language, snippet.LanguageConfidence = form.language()
+	if err = app.snippets.Insert(snippet, ""); err != nil {
+		app.serverError(w, err)
+		return
+	}
+	location := fmt.Sprintf("%s/s/%s", app.baseURL, snippet.Slug)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusCreated)
+	io.WriteString(w, location+"\n")
+}
+
+// bearerToken extracts the token from an Authorization header using the
+// Bearer scheme.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// clientIP returns the address of the client without its port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/ratelimit"
+)
+
+func TestPaste(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	bearer := func(token string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+	tests := []struct {
+		name     string
+		query    string
+		header   http.Header
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Anonymous",
+			body:     "echo hello",
+			wantCode: http.StatusCreated,
+			wantBody: "/s/n3wSn1pp3t\n",
+		},
+		{
+			name:     "API token",
+			query:    "?title=Hello&language=go&expires=12h&visibility=private",
+			header:   bearer("sb_valid"),
+			body:     "package main",
+			wantCode: http.StatusCreated,
+			wantBody: "/s/n3wSn1pp3t\n",
+		},
+		{
+			name:     "Invalid API token",
+			header:   bearer("sb_invalid"),
+			body:     "echo hello",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Other authorization scheme",
+			header:   http.Header{"Authorization": {"Basic YWxpY2U6cGFzcw=="}},
+			body:     "echo hello",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Never expires",
+			query:    "?expires=never",
+			body:     "echo hello",
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "Invalid expiry",
+			query:    "?expires=tomorrow",
+			body:     "echo hello",
+			wantCode: http.StatusBadRequest,
+			wantBody: "expires: This field must be a number followed by m, h or d, or never",
+		},
+		{
+			name:     "Expiry too far",
+			query:    "?expires=400d",
+			body:     "echo hello",
+			wantCode: http.StatusBadRequest,
+			wantBody: "expires: This field cannot be more than",
+		},
+		{
+			name:     "Unknown language",
+			query:    "?language=cobol",
+			body:     "echo hello",
+			wantCode: http.StatusBadRequest,
+			wantBody: "language: This field must be one of the listed languages",
+		},
+		{
+			name:     "Anonymous private",
+			query:    "?visibility=private",
+			body:     "echo hello",
+			wantCode: http.StatusBadRequest,
+			wantBody: "visibility: Anonymous pastes cannot be private",
+		},
+		{
+			name:     "Blank",
+			body:     " \n",
+			wantCode: http.StatusBadRequest,
+			wantBody: "content: This field cannot be blank",
+		},
+		{
+			name:     "Binary",
+			body:     "\xff\xfe\x00",
+			wantCode: http.StatusBadRequest,
+			wantBody: "content: This field must be UTF-8 text",
+		},
+		{
+			name:     "Too large",
+			body:     strings.Repeat("a", maxContentSize+1),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.post(t, "/paste"+tt.query, tt.header, tt.body)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, string(body), tt.wantBody)
+			if code == http.StatusCreated {
+				assert.Equal(t, headers.Get("Location"), "https://snippetbox.example/s/n3wSn1pp3t")
+				assert.Equal(t, headers.Get("Content-Type"), "text/plain; charset=utf-8")
+			}
+		})
+	}
+}
+
+func TestPasteRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+	app.pasteLimiter = ratelimit.New(2, time.Hour)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		code, _, _ := ts.post(t, "/paste", nil, "echo hello")
+		assert.Equal(t, code, http.StatusCreated)
+	}
+	code, _, _ := ts.post(t, "/paste", nil, "echo hello")
+	assert.Equal(t, code, http.StatusTooManyRequests)
+
+	// Pastes with an API token aren't limited.
+	header := http.Header{"Authorization": {"Bearer sb_valid"}}
+	code, _, _ = ts.post(t, "/paste", header, "echo hello")
+	assert.Equal(t, code, http.StatusCreated)
+}
+
+func TestPasteForm(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		wantTitle      string
+		wantVisibility models.Visibility
+		wantExpires    int
+		wantUnit       string
+	}{
+		{
+			name:           "Defaults",
+			wantTitle:      "Untitled",
+			wantVisibility: models.VisibilityUnlisted,
+			wantExpires:    7,
+			wantUnit:       "days",
+		},
+		{
+			name:           "Minutes",
+			query:          "title=Notes&visibility=public&expires=30m",
+			wantTitle:      "Notes",
+			wantVisibility: models.VisibilityPublic,
+			wantExpires:    30,
+			wantUnit:       "minutes",
+		},
+		{
+			name:           "Hours",
+			query:          "expires=12h",
+			wantTitle:      "Untitled",
+			wantVisibility: models.VisibilityUnlisted,
+			wantExpires:    12,
+			wantUnit:       "hours",
+		},
+		{
+			name:           "Never",
+			query:          "expires=never",
+			wantTitle:      "Untitled",
+			wantVisibility: models.VisibilityUnlisted,
+			wantExpires:    7,
+			wantUnit:       "never",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			assert.NilError(t, err)
+			form := pasteForm(query, "echo hello")
+			assert.Equal(t, form.Valid(), true)
+			assert.Equal(t, form.Title, tt.wantTitle)
+			assert.Equal(t, form.Visibility, tt.wantVisibility)
+			assert.Equal(t, form.Expires, tt.wantExpires)
+			assert.Equal(t, form.ExpiresUnit, tt.wantUnit)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/xyedo/snippetbox/internal/mailer"
+	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/validator"
+)
+
+// resetTTL is how long password reset links work.
+const resetTTL = time.Hour
+
+type forgotPasswordForm struct {
+	Email               string `form:"email"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) forgotPasswordView(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = forgotPasswordForm{}
+	app.render(w, http.StatusOK, "forgot.tmpl", data)
+}
+
+// forgotPasswordPost mails a password reset link to the given address if it
+// belongs to an account. The response must not tell whether it does, not
+// even by how long it takes, so the link is created and sent in the
+// background and the reply is the same either way.
+func (app *application) forgotPasswordPost(w http.ResponseWriter, r *http.Request) {
+	var form forgotPasswordForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.Email = strings.TrimSpace(form.Email)
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "forgot.tmpl", data)
+		return
+	}
+
+	// Requests over the limit are dropped quietly, so that the form can't
+	// be used to flood someone's inbox.
+	if app.resetLimiter.Allow(strings.ToLower(form.Email)) {
+		link := app.baseURL + "/user/password/reset/"
+		app.background("password reset email", func() error {
+			user, token, err := app.passwordResets.New(form.Email, resetTTL)
+			if err != nil {
+				if errors.Is(err, models.ErrNoRecord) {
+					return nil
+				}
+				return err
+			}
+			return app.mailer.Send(mailer.Message{
+				To:      user.Email,
+				Subject: "Reset your password",
+				Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your Snippetbox account. To choose a new one, open the link below within %s:\n\n%s%s\n\nThe link works once. If you didn't ask for it, you can ignore this email and your password stays the same.\n",
+					user.Name, humanDuration(resetTTL), link, token),
+			})
+		})
+	}
+	app.sessionManager.Put(r.Context(), "flash", "If an account uses that email address, we've sent it a link to reset the password.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+type resetPasswordForm struct {
+	Token               string `form:"-"`
+	NewPassword         string `form:"newPassword"`
+	NewPasswordConfirm  string `form:"newPasswordConfirmation"`
+	validator.Validator `form:"-"`
+}
+
+// resetPasswordView shows the form to choose a new password, or a page
+// saying the link is invalid or has expired. That page is rendered with a
+// nil form.
+func (app *application) resetPasswordView(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	token := params.ByName("token")
+	if !app.checkResetToken(w, r, token) {
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Form = resetPasswordForm{Token: token}
+	app.render(w, http.StatusOK, "reset.tmpl", data)
+}
+
+// checkResetToken reports whether token can reset a password. When it
+// can't, the response has already been written.
+func (app *application) checkResetToken(w http.ResponseWriter, r *http.Request, token string) bool {
+	_, err := app.passwordResets.Check(token)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			app.render(w, http.StatusBadRequest, "reset.tmpl", app.newTemplateData(r))
+			return false
+		}
+		app.serverError(w, err)
+		return false
+	}
+	return true
+}
+
+// resetPasswordPost sets the new password and logs the user out everywhere
+// else, since whoever made them reset it may still be logged in.
+func (app *application) resetPasswordPost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	form := resetPasswordForm{Token: params.ByName("token")}
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if !app.checkResetToken(w, r, form.Token) {
+		return
+	}
+	form.CheckField(validator.NotBlank(form.NewPassword), "newPassword", "This field cannot be blank")
+	form.CheckField(validator.MinChars(form.NewPassword, 8), "newPassword", "This field must be at least 8 characters long")
+	form.CheckField(form.NewPassword == form.NewPasswordConfirm, "newPasswordConfirmation", "New Password do not match")
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "reset.tmpl", data)
+		return
+	}
+
+	userID, err := app.passwordResets.Reset(form.Token, form.NewPassword)
+	if err != nil {
+		// Another request used the token in the meantime.
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			app.render(w, http.StatusBadRequest, "reset.tmpl", app.newTemplateData(r))
+			return
+		}
+		app.serverError(w, err)
+		return
+	}
+	if err = app.endOtherSessions(r, userID); err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if err = app.sessionManager.RenewToken(r.Context()); err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Your password has been reset. Please log in")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+// endOtherSessions destroys every session the user is logged in with, other
+// than the one of the request.
+func (app *application) endOtherSessions(r *http.Request, userID int) error {
+	current := app.sessionManager.Token(r.Context())
+	return app.sessionManager.Iterate(r.Context(), func(ctx context.Context) error {
+		if app.sessionManager.Token(ctx) == current || app.sessionManager.GetInt(ctx, "authenticateUserID") != userID {
+			return nil
+		}
+		return app.sessionManager.Destroy(ctx)
+	})
+}
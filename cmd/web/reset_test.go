@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/mailer"
+)
+
+func TestForgotPassword(t *testing.T) {
+	app := newTestApplication(t)
+	outbox := app.mailer.(*mailer.Outbox)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/password/forgot")
+	csrfToken := extractCSRFToken(t, body)
+	forgot := func(email string) (int, http.Header, []byte) {
+		form := url.Values{}
+		form.Add("email", email)
+		form.Add("csrf_token", csrfToken)
+		code, headers, _ := ts.postForm(t, "/user/password/forgot", form)
+		_, _, body := ts.get(t, "/user/login")
+		return code, headers, body
+	}
+
+	code, _, body := ts.postForm(t, "/user/password/forgot", url.Values{"email": {"alice@"}, "csrf_token": {csrfToken}})
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, string(body), "This field must be a valid email address")
+
+	// Known and unknown addresses get the same response.
+	knownCode, knownHeaders, knownBody := forgot("alice@example.com")
+	unknownCode, unknownHeaders, unknownBody := forgot("nobody@example.com")
+	assert.Equal(t, knownCode, http.StatusSeeOther)
+	assert.Equal(t, unknownCode, knownCode)
+	assert.Equal(t, unknownHeaders.Get("Location"), knownHeaders.Get("Location"))
+	assert.StringContains(t, string(knownBody), "If an account uses that email address, we&#39;ve sent it a link to reset the password.")
+	// CSRF tokens are masked differently in every response.
+	assert.Equal(t, string(csrfTokenRX.ReplaceAll(unknownBody, nil)), string(csrfTokenRX.ReplaceAll(knownBody, nil)))
+
+	app.jobs.Wait()
+	assert.Equal(t, len(outbox.Messages()), 1)
+	msg, ok := outbox.Last("alice@example.com")
+	assert.Equal(t, ok, true)
+	assert.StringContains(t, msg.Body, "https://snippetbox.example/user/password/reset/r3s3tT0k3n\n")
+	assert.StringContains(t, msg.Body, "within 1 hour:")
+
+	// Only a few links are sent to an address per hour.
+	for i := 0; i < 3; i++ {
+		code, _, _ = forgot("alice@example.com")
+		assert.Equal(t, code, http.StatusSeeOther)
+	}
+	app.jobs.Wait()
+	assert.Equal(t, len(outbox.Messages()), 3)
+}
+
+func TestResetPassword(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// useJar switches the test client to another browser.
+	useJar := func(jar http.CookieJar) {
+		ts.Client().Jar = jar
+	}
+	newJar := func() http.CookieJar {
+		jar, err := cookiejar.New(nil)
+		assert.NilError(t, err)
+		return jar
+	}
+	alice := newJar()
+	useJar(alice)
+	ts.login(t)
+	bob := newJar()
+	useJar(bob)
+	ts.loginAs(t, "bob@example.com")
+
+	useJar(newJar())
+	code, _, body := ts.get(t, "/user/password/reset/wr0ngT0k3n")
+	assert.Equal(t, code, http.StatusBadRequest)
+	assert.StringContains(t, string(body), "This password reset link is invalid, has expired or was already used.")
+
+	code, _, body = ts.get(t, "/user/password/reset/r3s3tT0k3n")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, string(body), "<form action='/user/password/reset/r3s3tT0k3n' method='POST' novalidate>")
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	form.Add("newPassword", "n3wPa$$word")
+	form.Add("newPasswordConfirmation", "n0tTheSame")
+	code, _, body = ts.postForm(t, "/user/password/reset/r3s3tT0k3n", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, string(body), "New Password do not match")
+
+	code, _, _ = ts.postForm(t, "/user/password/reset/wr0ngT0k3n", form)
+	assert.Equal(t, code, http.StatusBadRequest)
+
+	form.Set("newPasswordConfirmation", "n3wPa$$word")
+	code, headers, _ := ts.postForm(t, "/user/password/reset/r3s3tT0k3n", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/login")
+
+	// Alice was logged out everywhere, Bob wasn't.
+	useJar(alice)
+	code, headers, _ = ts.get(t, "/account/view")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/login")
+	useJar(bob)
+	code, _, _ = ts.get(t, "/account/view")
+	assert.Equal(t, code, http.StatusOK)
+}
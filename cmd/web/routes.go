@@ -2,8 +2,10 @@ package main
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/xyedo/snippetbox/internal/models"
 	"github.com/xyedo/snippetbox/ui"
 )
 
@@ -11,32 +13,112 @@ func (app *application) routes() http.Handler {
 	router := httprouter.New()
 
 	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIRequest(r) {
+			app.apiNotFound(w)
+			return
+		}
 		app.notFound(w)
 	})
+	router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIRequest(r) {
+			app.apiMethodNotAllowed(w, r)
+			return
+		}
+		app.clientError(w, http.StatusMethodNotAllowed)
+	})
 	fileServer := http.FileServer(http.FS(ui.Files))
 
 	router.Handler(http.MethodGet, "/static/*filepath", fileServer)
+	// Pages can be driven by API tokens as well as sessions. Tokens without
+	// the read scope see the pages that show snippets as a visitor would,
+	// and protected pages take the scope of their action.
 	dynamicmiddleware := func(fun http.Handler) http.Handler {
-		return app.sessionManager.LoadAndSave(NoSurf(app.authenticate(fun)))
+		return app.sessionManager.LoadAndSave(app.authenticateToken(NoSurf(app.authenticate(fun))))
+	}
+	readable := func(fun http.Handler) http.Handler {
+		return dynamicmiddleware(app.readAsVisitor(fun))
 	}
-	router.Handler(http.MethodGet, "/", dynamicmiddleware(http.HandlerFunc(app.home)))
-	router.Handler(http.MethodGet, "/snippet/view/:id", dynamicmiddleware(http.HandlerFunc(app.snippetView)))
+	router.Handler(http.MethodGet, "/", readable(http.HandlerFunc(app.home)))
+	router.Handler(http.MethodGet, "/snippet/view/:id", readable(http.HandlerFunc(app.snippetView)))
+	router.Handler(http.MethodGet, "/snippet/raw/:id", readable(http.HandlerFunc(app.snippetRaw)))
+	router.Handler(http.MethodGet, "/snippet/download/:id", readable(http.HandlerFunc(app.snippetDownload)))
+	router.Handler(http.MethodGet, "/snippet/view/:id/history", readable(http.HandlerFunc(app.snippetHistory)))
+	router.Handler(http.MethodGet, "/snippet/view/:id/diff", readable(http.HandlerFunc(app.snippetDiff)))
+	router.Handler(http.MethodGet, "/s/:slug", readable(http.HandlerFunc(app.snippetView)))
+	router.Handler(http.MethodPost, "/s/:slug", readable(http.HandlerFunc(app.snippetBurnPost)))
+	router.Handler(http.MethodPost, "/s/:slug/unlock", readable(http.HandlerFunc(app.snippetUnlockPost)))
+	router.Handler(http.MethodGet, "/s/:slug/history", readable(http.HandlerFunc(app.snippetHistory)))
+	router.Handler(http.MethodGet, "/s/:slug/diff", readable(http.HandlerFunc(app.snippetDiff)))
+	router.Handler(http.MethodGet, "/snippets", readable(http.HandlerFunc(app.snippetArchive)))
+	router.Handler(http.MethodGet, "/tag/:name", readable(http.HandlerFunc(app.tagView)))
+	router.Handler(http.MethodGet, "/search", readable(http.HandlerFunc(app.searchView)))
 	router.Handler(http.MethodGet, "/user/signup", dynamicmiddleware(http.HandlerFunc(app.userSignupView)))
 	router.Handler(http.MethodPost, "/user/signup", dynamicmiddleware(http.HandlerFunc(app.userSignupPost)))
 	router.Handler(http.MethodGet, "/user/login", dynamicmiddleware(http.HandlerFunc(app.userLoginView)))
 	router.Handler(http.MethodPost, "/user/login", dynamicmiddleware(http.HandlerFunc(app.userLoginPost)))
+	router.Handler(http.MethodGet, "/user/password/forgot", dynamicmiddleware(http.HandlerFunc(app.forgotPasswordView)))
+	router.Handler(http.MethodPost, "/user/password/forgot", dynamicmiddleware(http.HandlerFunc(app.forgotPasswordPost)))
+	router.Handler(http.MethodGet, "/user/password/reset/:token", dynamicmiddleware(http.HandlerFunc(app.resetPasswordView)))
+	router.Handler(http.MethodPost, "/user/password/reset/:token", dynamicmiddleware(http.HandlerFunc(app.resetPasswordPost)))
+	router.Handler(http.MethodGet, "/user/verify/:token", dynamicmiddleware(http.HandlerFunc(app.verifyEmail)))
 	router.Handler(http.MethodGet, "/about", dynamicmiddleware(http.HandlerFunc(app.aboutView)))
-	protected := func(fun http.Handler) http.Handler {
-		return dynamicmiddleware(app.requireAuth(fun))
+	protected := func(scope models.Scope, fun http.Handler) http.Handler {
+		return dynamicmiddleware(app.requireAuth(app.requireScope(scope, fun)))
+	}
+	// The account itself can only be managed from a session.
+	account := func(fun http.Handler) http.Handler {
+		return dynamicmiddleware(app.requireAuth(app.requireSession(fun)))
 	}
-	router.Handler(http.MethodGet, "/account/view", protected(http.HandlerFunc(app.accountView)))
-	router.Handler(http.MethodGet, "/account/password/update", protected(http.HandlerFunc(app.updatePasswordView)))
-	router.Handler(http.MethodPost, "/account/password/update", protected(http.HandlerFunc(app.updatePasswordPost)))
+	router.Handler(http.MethodGet, "/account/view", account(http.HandlerFunc(app.accountView)))
+	router.Handler(http.MethodPost, "/account/tokens", account(http.HandlerFunc(app.tokenCreatePost)))
+	router.Handler(http.MethodPost, "/account/tokens/delete/:id", account(http.HandlerFunc(app.tokenDeletePost)))
+	router.Handler(http.MethodPost, "/account/webhooks", account(http.HandlerFunc(app.webhookCreatePost)))
+	router.Handler(http.MethodPost, "/account/webhooks/delete/:id", account(http.HandlerFunc(app.webhookDeletePost)))
+	router.Handler(http.MethodPost, "/account/verify", account(http.HandlerFunc(app.verifyResendPost)))
+	router.Handler(http.MethodGet, "/account/password/update", account(http.HandlerFunc(app.updatePasswordView)))
+	router.Handler(http.MethodPost, "/account/password/update", account(http.HandlerFunc(app.updatePasswordPost)))
 
-	router.Handler(http.MethodGet, "/snippet/create", protected(http.HandlerFunc(app.snippetCreateView)))
-	router.Handler(http.MethodPost, "/snippet/create", protected(http.HandlerFunc(app.createSnippetPost)))
-	router.Handler(http.MethodPost, "/user/logout", protected(http.HandlerFunc(app.logoutUserPost)))
+	// Creating snippets takes a verified email address.
+	router.Handler(http.MethodGet, "/snippet/create", protected(models.ScopeWrite, app.requireVerified(http.HandlerFunc(app.snippetCreateView))))
+	router.Handler(http.MethodPost, "/snippet/create", protected(models.ScopeWrite, app.requireVerified(http.HandlerFunc(app.createSnippetPost))))
+	router.Handler(http.MethodGet, "/snippet/edit/:id", protected(models.ScopeWrite, http.HandlerFunc(app.snippetEditView)))
+	router.Handler(http.MethodPost, "/snippet/edit/:id", protected(models.ScopeWrite, http.HandlerFunc(app.snippetEditPost)))
+	router.Handler(http.MethodPost, "/snippet/language/:id", protected(models.ScopeWrite, http.HandlerFunc(app.snippetLanguagePost)))
+	router.Handler(http.MethodPost, "/snippet/delete/:id", protected(models.ScopeDelete, http.HandlerFunc(app.snippetDeletePost)))
+	router.Handler(http.MethodPost, "/user/logout", account(http.HandlerFunc(app.logoutUserPost)))
+	for _, op := range apiOperations() {
+		router.Handler(op.Method, op.Path, app.apiRoute(op))
+	}
 	router.Handler(http.MethodGet, "/ping", http.HandlerFunc(ping))
 
 	return app.recoverPanic(app.logRequest(secureHeaders(router)))
 }
+
+// apiRoute returns the handler of an API operation wrapped in the middleware
+// its description calls for. Pastes and the API serve scripts rather than
+// browsers, so they skip the session and CSRF middleware and only
+// authenticate API tokens.
+func (app *application) apiRoute(op apiOperation) http.Handler {
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op.Handler(app, w, r)
+	})
+	if op.Verified {
+		h = app.requireVerified(h)
+	}
+	if op.Scope != "" {
+		h = app.requireScope(op.Scope, h)
+	}
+	switch op.Auth {
+	case authOptional:
+		h = app.authenticateToken(h)
+	case authRequired:
+		h = app.authenticateToken(app.apiRequireAuth(h))
+	}
+	return h
+}
+
+// isAPIRequest reports whether a request is for the JSON API, whose errors
+// are JSON as well.
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xyedo/snippetbox/internal/models"
+)
+
+// fragment is a piece of text that either matched a search term or not.
+// Templates wrap the matching ones in <mark>, so the text itself is still
+// escaped as usual.
+type fragment struct {
+	Text  string
+	Match bool
+}
+
+// searchResult is a snippet found by a search with its title and an excerpt
+// of its content split into fragments.
+type searchResult struct {
+	Snippet *models.Snippet
+	Title   []fragment
+	Excerpt []fragment
+}
+
+// excerptLength is roughly how many characters of content a search result
+// shows.
+const excerptLength = 240
+
+// searchTerms splits a query into the words that are highlighted in results.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// termsRX returns a case-insensitive pattern matching any of terms, or nil if
+// there are none. Longer terms come first so that they win over their
+// prefixes.
+func termsRX(terms []string) *regexp.Regexp {
+	if len(terms) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+	sort.Slice(quoted, func(i, j int) bool {
+		return len(quoted[i]) > len(quoted[j])
+	})
+	return regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+}
+
+// highlight splits text into fragments around the matches of rx.
+func highlight(text string, rx *regexp.Regexp) []fragment {
+	var fragments []fragment
+	last := 0
+	if rx != nil {
+		for _, m := range rx.FindAllStringIndex(text, -1) {
+			if m[0] > last {
+				fragments = append(fragments, fragment{Text: text[last:m[0]]})
+			}
+			fragments = append(fragments, fragment{Text: text[m[0]:m[1]], Match: true})
+			last = m[1]
+		}
+	}
+	if last < len(text) {
+		fragments = append(fragments, fragment{Text: text[last:]})
+	}
+	return fragments
+}
+
+// excerpt cuts about excerptLength characters out of text, starting a little
+// before the first match of rx, and highlights the matches in it.
+func excerpt(text string, rx *regexp.Regexp) []fragment {
+	start := 0
+	if rx != nil {
+		if m := rx.FindStringIndex(text); m != nil {
+			// Keep some context before the match, starting at a rune
+			// boundary.
+			start = m[0] - excerptLength/4
+			if start < 0 {
+				start = 0
+			}
+			for start > 0 && !utf8.RuneStart(text[start]) {
+				start--
+			}
+		}
+	}
+	end := start
+	for n := 0; end < len(text) && n < excerptLength; n++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	fragments := highlight(text[start:end], rx)
+	if start > 0 {
+		fragments = append([]fragment{{Text: "…"}}, fragments...)
+	}
+	if end < len(text) {
+		fragments = append(fragments, fragment{Text: "…"})
+	}
+	return fragments
+}
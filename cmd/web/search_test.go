@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+// renderFragments marks the matching fragments with brackets.
+func renderFragments(fragments []fragment) string {
+	var sb strings.Builder
+	for _, f := range fragments {
+		if f.Match {
+			sb.WriteString("[" + f.Text + "]")
+		} else {
+			sb.WriteString(f.Text)
+		}
+	}
+	return sb.String()
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{
+			name:  "Case-insensitive",
+			text:  "An old silent Pond, a frog jumps into the pond",
+			query: "pond",
+			want:  "An old silent [Pond], a frog jumps into the [pond]",
+		},
+		{
+			name:  "Several terms",
+			text:  "func main() { fmt.Println() }",
+			query: "fmt main",
+			want:  "func [main]() { [fmt].Println() }",
+		},
+		{
+			name:  "Longest term wins",
+			text:  "gopher",
+			query: "go gopher",
+			want:  "[gopher]",
+		},
+		{
+			name:  "Special characters are literal",
+			text:  "a.b (c)",
+			query: "(c)",
+			want:  "a.b ([c])",
+		},
+		{
+			name:  "No terms",
+			text:  "nothing to see",
+			query: "  ",
+			want:  "nothing to see",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderFragments(highlight(tt.text, termsRX(searchTerms(tt.query))))
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	rx := termsRX([]string{"needle"})
+
+	short := "a needle in a small haystack"
+	assert.Equal(t, renderFragments(excerpt(short, rx)), "a [needle] in a small haystack")
+
+	long := strings.Repeat("hay ", 100) + "needle" + strings.Repeat(" stack", 100)
+	got := renderFragments(excerpt(long, rx))
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("got %q; want an excerpt cut on both sides", got)
+	}
+	assert.StringContains(t, got, "hay [needle] stack")
+
+	// Cutting must not split multi-byte characters.
+	wide := strings.Repeat("é", 400) + "needle"
+	got = renderFragments(excerpt(wide, rx))
+	assert.StringContains(t, got, "[needle]")
+	assert.Equal(t, strings.ToValidUTF8(got, "?"), got)
+}
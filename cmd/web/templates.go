@@ -1,24 +1,113 @@
 package main
 
 import (
+	"fmt"
 	"html/template"
 	"io/fs"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/xyedo/snippetbox/internal/diff"
 	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/syntax"
 	"github.com/xyedo/snippetbox/ui"
 )
 
 type templateData struct {
-	IsAuthenticated bool
-	CSRFToken       string
-	CurrentYear     int
-	Flash           string
-	Form            any
-	Snippet         *models.Snippet
-	Snippets        []*models.Snippet
-	User            *models.User
+	IsAuthenticated     bool
+	AuthenticatedUserID int
+	CSRFToken           string
+	CurrentYear         int
+	Flash               string
+	Form                any
+	Snippet             *models.Snippet
+	Snippets            []*models.Snippet
+	Revisions           []*models.Revision
+	Diff                *revisionDiff
+	User                *models.User
+	Tokens              []*models.Token
+	Webhooks            []*models.Webhook
+	Deliveries          []*models.Delivery
+	Tag                 string
+	TagCloud            []tagCloudEntry
+	Pagination          *pagination
+	Archive             *archivePage
+	Query               string
+	Results             []*searchResult
+	// NewToken is the plaintext of an API token that was just created.
+	NewToken string
+	// WebhookForm is the webhook form on the account page, which also has
+	// the token form in Form.
+	WebhookForm any
+	// NewWebhookSecret is the secret of a webhook that was just created.
+	NewWebhookSecret string
+}
+
+// pagination links to the neighbours of a page of an offset-paginated list.
+// Prev and Next are zero when there is no such page.
+type pagination struct {
+	Page int
+	Prev int
+	Next int
+}
+
+// archivePage describes a page of the snippet archive. Prev and Next are
+// the cursors of the neighbouring pages, empty when there is no such page.
+type archivePage struct {
+	Order models.SnippetOrder
+	Size  int
+	Prev  string
+	Next  string
+}
+
+// tagCloudSize is how many tags the home page shows.
+const tagCloudSize = 30
+
+// tagCloudEntry is a tag with a weight from 1 to 5 that sets its size in the
+// tag cloud.
+type tagCloudEntry struct {
+	Name   string
+	Count  int
+	Weight int
+}
+
+// newTagCloud weighs tags relative to the most used one and sorts them by
+// name.
+func newTagCloud(tags []*models.Tag) []tagCloudEntry {
+	max := 0
+	for _, t := range tags {
+		if t.Count > max {
+			max = t.Count
+		}
+	}
+	cloud := make([]tagCloudEntry, 0, len(tags))
+	for _, t := range tags {
+		weight := 1
+		if max > 1 {
+			weight = 1 + 4*(t.Count-1)/(max-1)
+		}
+		cloud = append(cloud, tagCloudEntry{
+			Name:   t.Name,
+			Count:  t.Count,
+			Weight: weight,
+		})
+	}
+	sort.Slice(cloud, func(i, j int) bool {
+		return cloud[i].Name < cloud[j].Name
+	})
+	return cloud
+}
+
+// revisionDiff holds the changes between two revisions of a snippet.
+// TooDifferent is set instead of Hunks for revisions that differ too much to
+// be compared line by line.
+type revisionDiff struct {
+	From         *models.Revision
+	To           *models.Revision
+	Hunks        []diff.Hunk
+	TooDifferent bool
 }
 
 func humanDate(t time.Time) string {
@@ -28,8 +117,87 @@ func humanDate(t time.Time) string {
 	return t.UTC().Format("02 Jan 2006 at 15:04")
 }
 
+// humanDuration formats d using its two largest units, e.g. "2 days 4 hours".
+func humanDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	var parts []string
+	for i, u := range units {
+		if d < u.size {
+			continue
+		}
+		parts = append(parts, plural(int(d/u.size), u.name))
+		if i+1 < len(units) {
+			if n := int(d % u.size / units[i+1].size); n > 0 {
+				parts = append(parts, plural(n, units[i+1].name))
+			}
+		}
+		break
+	}
+	return strings.Join(parts, " ")
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// timeUntil formats the time left until t.
+func timeUntil(t time.Time) string {
+	return humanDuration(time.Until(t))
+}
+
+// languageLabel returns the name users know the language called name by.
+func languageLabel(name string) string {
+	if l, ok := syntax.Lookup(name); ok {
+		return l.Label
+	}
+	return "Plain text"
+}
+
+// languageChoice returns the value of the language field that keeps the
+// current language of s: empty if it was detected, so that it is detected
+// again when the content changes.
+func languageChoice(s *models.Snippet) string {
+	switch {
+	case s.LanguageConfidence > 0:
+		return ""
+	case s.Language == "":
+		return plainText
+	}
+	return s.Language
+}
+
+// percent formats a fraction between 0 and 1 as a whole percentage.
+func percent(f float64) string {
+	return fmt.Sprintf("%.0f%%", f*100)
+}
+
+// webhookEvents lists the events webhooks can subscribe to.
+func webhookEvents() []models.Event {
+	return models.Events
+}
+
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":      humanDate,
+	"timeUntil":      timeUntil,
+	"languages":      syntax.Languages,
+	"languageLabel":  languageLabel,
+	"languageChoice": languageChoice,
+	"percent":        percent,
+	"highlight":      syntax.Tokenize,
+	"webhookEvents":  webhookEvents,
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/models"
 )
 
 func TestHumanDate(t *testing.T) {
@@ -36,3 +37,40 @@ func TestHumanDate(t *testing.T) {
 		})
 	}
 }
+
+func TestHumanDuration(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want string
+	}{
+		{name: "Seconds", d: 30 * time.Second, want: "less than a minute"},
+		{name: "One minute", d: time.Minute, want: "1 minute"},
+		{name: "Hours and minutes", d: 2*time.Hour + 5*time.Minute + 10*time.Second, want: "2 hours 5 minutes"},
+		{name: "Whole days", d: 365 * 24 * time.Hour, want: "365 days"},
+		{name: "Smaller units are dropped", d: 24*time.Hour + 59*time.Minute, want: "1 day"},
+		{name: "Days and hours", d: 50 * time.Hour, want: "2 days 2 hours"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, humanDuration(tt.d), tt.want)
+		})
+	}
+}
+
+func TestNewTagCloud(t *testing.T) {
+	cloud := newTagCloud([]*models.Tag{
+		{Name: "go", Count: 9},
+		{Name: "sql", Count: 5},
+		{Name: "css", Count: 1},
+	})
+	want := []tagCloudEntry{
+		{Name: "css", Count: 1, Weight: 1},
+		{Name: "go", Count: 9, Weight: 5},
+		{Name: "sql", Count: 5, Weight: 3},
+	}
+	assert.Equal(t, len(cloud), len(want))
+	for i := range want {
+		assert.Equal(t, cloud[i], want[i])
+	}
+}
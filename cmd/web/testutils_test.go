@@ -9,12 +9,16 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
+	"github.com/xyedo/snippetbox/internal/mailer"
 	"github.com/xyedo/snippetbox/internal/models/mock"
+	"github.com/xyedo/snippetbox/internal/ratelimit"
+	"github.com/xyedo/snippetbox/internal/search"
 )
 
 func newTestApplication(t *testing.T) *application {
@@ -26,14 +30,35 @@ func newTestApplication(t *testing.T) *application {
 	sessionManager := scs.New()
 	sessionManager.Lifetime = 12 * time.Hour
 	sessionManager.Cookie.Secure = true
+	// Searches go through the in-process index, as they do by default.
+	snippets := &search.Snippets{SnippetModelInterface: &mock.SnippetModel{}, Index: search.NewMemory()}
+	if _, err := snippets.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
 	return &application{
+		// Numeric IDs are off by default, but the tests cover the legacy
+		// routes too.
+		numericIDs:     true,
+		maxExpiry:      365 * 24 * time.Hour,
+		pageSize:       20,
 		errorLog:       log.New(ioutil.Discard, "", 0),
 		infoLog:        log.New(ioutil.Discard, "", 0),
 		sessionManager: sessionManager,
-		snippets:       &mock.SnippetModel{},
+		snippets:       snippets,
 		templateCache:  templateCache,
 		users:          &mock.UserModel{},
+		tokens:         &mock.TokenModel{},
+		passwordResets: &mock.PasswordResetModel{},
+		webhooks:       &mock.WebhookModel{},
+		webhookClient:  newWebhookClient(dialPublicOnly),
+		mailer:         &mailer.Outbox{},
+		secretKey:      []byte("a test key that is 32 bytes long"),
+		baseURL:        "https://snippetbox.example",
 		formDecoder:    fd,
+		unlockLimiter:  ratelimit.New(5, 15*time.Minute),
+		pasteLimiter:   ratelimit.New(10, time.Hour),
+		resetLimiter:   ratelimit.New(3, time.Hour),
+		loginLimiter:   ratelimit.New(5, 15*time.Minute),
 	}
 }
 
@@ -88,3 +113,53 @@ func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (i
 	}
 	return rs.StatusCode, rs.Header, body
 }
+
+// post sends a POST request with the given headers and raw body.
+func (ts *testServer) post(t *testing.T, urlPath string, header http.Header, body string) (int, http.Header, []byte) {
+	return ts.request(t, http.MethodPost, urlPath, header, body)
+}
+
+// request sends a request with any method, headers and raw body.
+func (ts *testServer) request(t *testing.T, method, urlPath string, header http.Header, body string) (int, http.Header, []byte) {
+	req, err := http.NewRequest(method, ts.URL+urlPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+	b, err := ioutil.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs.StatusCode, rs.Header, b
+}
+
+// login signs the test server's client in as the mock user and returns a CSRF
+// token that is valid for the rest of the session.
+func (ts *testServer) login(t *testing.T) string {
+	t.Helper()
+	return ts.loginAs(t, "alice@example.com")
+}
+
+// loginAs signs the test server's client in as the mock user with the given
+// email address.
+func (ts *testServer) loginAs(t *testing.T, email string) string {
+	t.Helper()
+	_, _, body := ts.get(t, "/user/login")
+	csrfToken := extractCSRFToken(t, body)
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", "pa$$word")
+	form.Add("csrf_token", csrfToken)
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("login failed with status %d", code)
+	}
+	return csrfToken
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/xyedo/snippetbox/internal/mailer"
+	"github.com/xyedo/snippetbox/internal/models"
+)
+
+// New users prove that their email address is theirs by following a link
+// mailed to it. The token in the link isn't stored: it carries the user's ID
+// and its expiry, signed together with the address with app.secretKey, so
+// it stops working once it expires or the address changes.
+
+// verificationTTL is how long verification links work.
+const verificationTTL = 24 * time.Hour
+
+// verificationToken returns the token that verifies email for the user with
+// the given ID until expires.
+func (app *application) verificationToken(userID int, email string, expires time.Time) string {
+	payload := strconv.Itoa(userID) + "." + strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, app.secretKey)
+	mac.Write([]byte("verify\n" + payload + "\n" + email))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseVerificationToken returns the user ID and expiry a token claims. The
+// claim can only be trusted once the token has been checked against the
+// user's address with verificationToken.
+func parseVerificationToken(token string) (userID int, expires time.Time, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, time.Time{}, false
+	}
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil || userID < 1 {
+		return 0, time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return userID, time.Unix(unix, 0), true
+}
+
+// sendVerification mails user a link to verify their address.
+func (app *application) sendVerification(user *models.User) error {
+	token := app.verificationToken(user.ID, user.Email, time.Now().Add(verificationTTL))
+	return app.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease verify your email address by opening the link below within %s:\n\n%s/user/verify/%s\n\nIf you didn't sign up for Snippetbox, you can ignore this email.\n",
+			user.Name, humanDuration(verificationTTL), app.baseURL, token),
+	})
+}
+
+// verifyEmail marks the address of the user a verification link was sent to
+// as verified. Invalid and expired links get the same page, which tells the
+// user how to get a new one.
+func (app *application) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	invalid := func() {
+		app.render(w, http.StatusBadRequest, "verify.tmpl", app.newTemplateData(r))
+	}
+	params := httprouter.ParamsFromContext(r.Context())
+	token := params.ByName("token")
+	id, expires, ok := parseVerificationToken(token)
+	if !ok || time.Now().After(expires) {
+		invalid()
+		return
+	}
+	user, err := app.users.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			invalid()
+			return
+		}
+		app.serverError(w, err)
+		return
+	}
+	if !hmac.Equal([]byte(token), []byte(app.verificationToken(user.ID, user.Email, expires))) {
+		invalid()
+		return
+	}
+	if !user.Verified {
+		if err = app.users.Verify(user.ID); err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Your email address has been verified!")
+	if app.isAuthenticated(r) {
+		http.Redirect(w, r, "/snippet/create", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+// verifyResendPost mails the user a new verification link, for when the
+// first one expired or got lost.
+func (app *application) verifyResendPost(w http.ResponseWriter, r *http.Request) {
+	user, err := app.users.Get(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if user.Verified {
+		app.sessionManager.Put(r.Context(), "flash", "Your email address is already verified.")
+		http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+		return
+	}
+	if err = app.sendVerification(user); err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("We've sent a new verification link to %s.", user.Email))
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+// requireVerified refuses requests from users who haven't verified their
+// email address yet. Browsers are sent to the account page, where a new link
+// can be requested. Anonymous requests are let through, for the routes that
+// allow them to check on their own.
+func (app *application) requireVerified(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, err := app.users.Get(app.authenticatedUserID(r))
+		if err != nil {
+			if isAPIRequest(r) {
+				app.apiServerError(w, err)
+				return
+			}
+			app.serverError(w, err)
+			return
+		}
+		if user.Verified {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if apiToken(r) != nil {
+			app.forbidden(w, r, "verify your email address before creating snippets")
+			return
+		}
+		app.sessionManager.Put(r.Context(), "flash", "Please verify your email address before creating snippets.")
+		http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+	}
+}
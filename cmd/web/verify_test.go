@@ -0,0 +1,187 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/mailer"
+)
+
+var verifyLinkRX = regexp.MustCompile(`https?://[^/\s]+(/user/verify/\S+)`)
+
+func TestEmailVerification(t *testing.T) {
+	app := newTestApplication(t)
+	outbox := app.mailer.(*mailer.Outbox)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/signup")
+	form := url.Values{}
+	form.Add("name", "Bob")
+	form.Add("email", "bob@example.com")
+	form.Add("password", "validPa$$word")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	code, _, _ := ts.postForm(t, "/user/signup", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	msg, ok := outbox.Last("bob@example.com")
+	assert.Equal(t, ok, true)
+	assert.StringContains(t, msg.Body, "Hi Bob,")
+	assert.StringContains(t, msg.Body, "within 1 day:")
+	matches := verifyLinkRX.FindStringSubmatch(msg.Body)
+	if matches == nil {
+		t.Fatalf("no verification link in %q", msg.Body)
+	}
+	// The link points to the configured base URL, not the host the request
+	// was sent to.
+	assert.StringContains(t, matches[0], "https://snippetbox.example/user/verify/")
+	link := matches[1]
+
+	// Until the address is verified, snippets can't be created.
+	csrfToken := ts.loginAs(t, "bob@example.com")
+	code, headers, _ := ts.get(t, "/snippet/create")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/account/view")
+	_, _, body = ts.get(t, "/account/view")
+	assert.StringContains(t, string(body), "Please verify your email address before creating snippets.")
+	assert.StringContains(t, string(body), "bob@example.com (not verified)")
+
+	form = url.Values{}
+	form.Add("csrf_token", csrfToken)
+	code, _, _ = ts.postForm(t, "/account/verify", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, len(outbox.Messages()), 2)
+
+	code, headers, _ = ts.get(t, link)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/snippet/create")
+}
+
+func TestVerifyEmailLinks(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	valid := app.verificationToken(3, "bob@example.com", time.Now().Add(time.Hour))
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{
+			name:     "Valid",
+			token:    valid,
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Expired",
+			token:    app.verificationToken(3, "bob@example.com", time.Now().Add(-time.Minute)),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Other address",
+			token:    app.verificationToken(3, "mallory@example.com", time.Now().Add(time.Hour)),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Other user",
+			token:    "1" + strings.TrimPrefix(valid, "3"),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unknown user",
+			token:    app.verificationToken(9, "bob@example.com", time.Now().Add(time.Hour)),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Tampered",
+			token:    valid + "x",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Malformed",
+			token:    "not-a-token",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.get(t, "/user/verify/"+tt.token)
+			assert.Equal(t, code, tt.wantCode)
+			if code == http.StatusSeeOther {
+				assert.Equal(t, headers.Get("Location"), "/user/login")
+				return
+			}
+			assert.StringContains(t, string(body), "This verification link is invalid or has expired.")
+		})
+	}
+}
+
+func TestRequireVerified(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "API",
+			method:   http.MethodPost,
+			path:     "/api/v1/snippets",
+			token:    "sb_unverified",
+			body:     `{"title": "Hello", "content": "package main"}`,
+			wantCode: http.StatusForbidden,
+			wantBody: `"message":"verify your email address before creating snippets"`,
+		},
+		{
+			name:     "Paste",
+			method:   http.MethodPost,
+			path:     "/paste",
+			token:    "sb_unverified",
+			body:     "package main",
+			wantCode: http.StatusForbidden,
+			wantBody: "verify your email address before creating snippets",
+		},
+		{
+			name:     "Anonymous paste",
+			method:   http.MethodPost,
+			path:     "/paste",
+			body:     "package main",
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "Other routes",
+			method:   http.MethodGet,
+			path:     "/api/v1/user",
+			token:    "sb_unverified",
+			wantCode: http.StatusOK,
+			wantBody: `"verified":false`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if strings.HasPrefix(tt.path, "/api/") {
+				header.Set("Content-Type", "application/json")
+			}
+			code, _, body := ts.request(t, tt.method, tt.path, header, tt.body)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, string(body), tt.wantBody)
+		})
+	}
+}
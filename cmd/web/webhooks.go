@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/validator"
+)
+
+// Webhooks post events on a user's snippets to the URLs they subscribed.
+// The snippet model queues the deliveries in the transaction that makes the
+// change, and deliverWebhooks sends them on a schedule, retrying failed ones
+// with exponential backoff. Deliveries are made at least once: one that was
+// interrupted by a shutdown is sent again after the restart.
+
+const (
+	// webhookBatch is how many due deliveries are loaded at a time.
+	webhookBatch = 100
+	// maxWebhookAttempts is how often a delivery is tried before it fails for
+	// good. With webhookRetryBase of a minute, the last attempt is made about
+	// two hours after the first.
+	maxWebhookAttempts = 8
+	webhookRetryBase   = time.Minute
+	// maxWebhookError is how much of an error the delivery log keeps.
+	maxWebhookError = 255
+	// deliveryLogSize is how many deliveries the account page lists.
+	deliveryLogSize = 20
+)
+
+// newWebhookClient returns the client deliveries are sent with. Redirects
+// count as failures rather than being followed, since a receiver that moved
+// should be subscribed again at its new URL.
+//
+// control is called with every address the client is about to connect to,
+// after DNS lookup, and stops the connection if it returns an error. Pass
+// dialPublicOnly so that webhooks can't be used to reach the server's own
+// network, even through a hostname that resolves to a private address only
+// once it has been validated. Proxies from the environment aren't used, as
+// they would hide the receiver's address.
+func newWebhookClient(control func(network, address string, c syscall.RawConn) error) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}).DialContext
+	return &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicIP reports whether ip can be reached from anywhere, rather than only
+// from the host itself or its local network.
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast())
+}
+
+// dialPublicOnly is a net.Dialer Control function that refuses connections
+// to addresses that aren't public.
+func dialPublicOnly(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%s is not a public address", host)
+	}
+	return nil
+}
+
+// webhookBackoff returns how long to wait before retrying a delivery that
+// failed attempts times: webhookRetryBase, doubling with each attempt.
+func webhookBackoff(attempts int) time.Duration {
+	return webhookRetryBase << (attempts - 1)
+}
+
+// signWebhook returns the signature of a payload that receivers check to
+// know the delivery came from us: the hex-encoded HMAC-SHA256 of the payload
+// keyed with the webhook's secret.
+func signWebhook(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhooks sends the deliveries that are due and records how each
+// attempt went.
+func (app *application) deliverWebhooks(ctx context.Context) error {
+	for {
+		deliveries, err := app.webhooks.Due(webhookBatch)
+		if err != nil {
+			return err
+		}
+		for _, d := range deliveries {
+			app.attemptDelivery(ctx, d)
+			// An attempt cut short by a shutdown isn't held against the
+			// receiver; the delivery stays due.
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err = app.webhooks.Record(d); err != nil {
+				return err
+			}
+		}
+		if len(deliveries) < webhookBatch {
+			return nil
+		}
+	}
+}
+
+// attemptDelivery posts a delivery to its webhook and updates it with the
+// outcome: delivered, scheduled for another attempt, or failed after
+// maxWebhookAttempts.
+func (app *application) attemptDelivery(ctx context.Context, d *models.Delivery) {
+	d.Attempts++
+	d.LastAttempt = time.Now().UTC().Truncate(time.Second)
+	d.ResponseStatus = 0
+	err := app.postWebhook(ctx, d)
+	if err == nil {
+		d.Status = models.DeliveryDelivered
+		d.Error = ""
+		return
+	}
+	d.Error = err.Error()
+	if len(d.Error) > maxWebhookError {
+		d.Error = strings.ToValidUTF8(d.Error[:maxWebhookError], "")
+	}
+	if d.Attempts >= maxWebhookAttempts {
+		d.Status = models.DeliveryFailed
+		return
+	}
+	d.NextAttempt = d.LastAttempt.Add(webhookBackoff(d.Attempts))
+}
+
+// postWebhook sends a delivery. Any response but a 2xx is an error.
+func (app *application) postWebhook(ctx context.Context, d *models.Delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Snippetbox-Webhook")
+	req.Header.Set("X-Snippetbox-Event", string(d.Event))
+	req.Header.Set("X-Snippetbox-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set("X-Snippetbox-Signature", signWebhook(d.Secret, d.Payload))
+	rs, err := app.webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rs.Body.Close()
+	// Draining the body lets the connection be reused.
+	io.Copy(io.Discard, io.LimitReader(rs.Body, 64<<10))
+	d.ResponseStatus = rs.StatusCode
+	if rs.StatusCode < 200 || rs.StatusCode > 299 {
+		return fmt.Errorf("receiver replied %d %s", rs.StatusCode, http.StatusText(rs.StatusCode))
+	}
+	return nil
+}
+
+type webhookCreateForm struct {
+	URL                 string         `form:"url"`
+	Events              []models.Event `form:"events"`
+	validator.Validator `form:"-"`
+}
+
+// Subscribes reports whether event is ticked on the form.
+func (form webhookCreateForm) Subscribes(event models.Event) bool {
+	for _, e := range form.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (form *webhookCreateForm) validate() {
+	form.CheckField(validator.NotBlank(form.URL), "url", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.URL, 2048), "url", "This field cannot be more than 2048 characters long")
+	u, err := url.Parse(form.URL)
+	form.CheckField(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "url", "This field must be an http or https URL")
+	if err == nil {
+		// Hostnames are checked again when deliveries are sent, once
+		// they are resolved.
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+		ip := net.ParseIP(host)
+		form.CheckField(host != "localhost" && !strings.HasSuffix(host, ".localhost") && (ip == nil || publicIP(ip)), "url", "This field must not point to a local or private address")
+	}
+	form.CheckField(len(form.Events) > 0, "events", "Pick at least one event")
+	for _, event := range form.Events {
+		form.CheckField(validator.PermittedValue(event, models.Events...), "events", "This field must only contain the listed events")
+	}
+}
+
+// webhookCreatePost subscribes a URL to events on the user's snippets. Like
+// tokenCreatePost it renders the account page straight away, since the
+// secret is shown only once.
+func (app *application) webhookCreatePost(w http.ResponseWriter, r *http.Request) {
+	var form webhookCreateForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.URL = strings.TrimSpace(form.URL)
+	form.validate()
+
+	data, ok := app.accountData(w, r)
+	if !ok {
+		return
+	}
+	if !form.Valid() {
+		data.WebhookForm = form
+		app.render(w, http.StatusUnprocessableEntity, "account.tmpl", data)
+		return
+	}
+	webhook, err := app.webhooks.Insert(app.authenticatedUserID(r), form.URL, form.Events)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	data.Webhooks = append([]*models.Webhook{webhook}, data.Webhooks...)
+	data.NewWebhookSecret = webhook.Secret
+	app.render(w, http.StatusOK, "account.tmpl", data)
+}
+
+func (app *application) webhookDeletePost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	err = app.webhooks.Delete(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+			return
+		}
+		app.serverError(w, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Webhook successfully deleted!")
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
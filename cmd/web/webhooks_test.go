@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/models/mock"
+)
+
+// queuedWebhooks pretends to hold a single due delivery and keeps what was
+// recorded about it.
+type queuedWebhooks struct {
+	mock.WebhookModel
+	due      []*models.Delivery
+	recorded []*models.Delivery
+}
+
+func (m *queuedWebhooks) Due(limit int) ([]*models.Delivery, error) {
+	due := m.due
+	m.due = nil
+	return due, nil
+}
+
+func (m *queuedWebhooks) Record(d *models.Delivery) error {
+	m.recorded = append(m.recorded, d)
+	return nil
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	payload := []byte(`{"event":"snippet.created","snippet":{"slug":"aB3dE5gH7j"}}`)
+	var received http.Header
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != string(payload) || r.Header.Get("X-Snippetbox-Signature") != signWebhook("s3cr3t", body) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusNoContent)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer receiver.Close()
+	// A server that is gone by the time deliveries are made.
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	tests := []struct {
+		name         string
+		url          string
+		publicOnly   bool
+		attempts     int
+		wantStatus   models.DeliveryStatus
+		wantResponse int
+		wantError    string
+		wantRetryIn  time.Duration
+	}{
+		{
+			name:         "Delivered",
+			url:          receiver.URL + "/ok",
+			wantStatus:   models.DeliveryDelivered,
+			wantResponse: http.StatusNoContent,
+		},
+		{
+			name:         "Receiver error",
+			url:          receiver.URL + "/broken",
+			wantStatus:   models.DeliveryPending,
+			wantResponse: http.StatusInternalServerError,
+			wantError:    "receiver replied 500 Internal Server Error",
+			wantRetryIn:  time.Minute,
+		},
+		{
+			name:         "Backoff",
+			url:          receiver.URL + "/broken",
+			attempts:     3,
+			wantStatus:   models.DeliveryPending,
+			wantResponse: http.StatusInternalServerError,
+			wantError:    "receiver replied 500 Internal Server Error",
+			wantRetryIn:  8 * time.Minute,
+		},
+		{
+			name:         "Last attempt",
+			url:          receiver.URL + "/broken",
+			attempts:     maxWebhookAttempts - 1,
+			wantStatus:   models.DeliveryFailed,
+			wantResponse: http.StatusInternalServerError,
+			wantError:    "receiver replied 500 Internal Server Error",
+		},
+		{
+			name:         "Redirect",
+			url:          receiver.URL + "/moved",
+			wantStatus:   models.DeliveryPending,
+			wantResponse: http.StatusFound,
+			wantError:    "receiver replied 302 Found",
+			wantRetryIn:  time.Minute,
+		},
+		{
+			name:        "Private address",
+			url:         receiver.URL + "/ok",
+			publicOnly:  true,
+			wantStatus:  models.DeliveryPending,
+			wantError:   "127.0.0.1 is not a public address",
+			wantRetryIn: time.Minute,
+		},
+		{
+			name:        "Unreachable",
+			url:         gone.URL,
+			wantStatus:  models.DeliveryPending,
+			wantError:   "connection refused",
+			wantRetryIn: time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			webhooks := &queuedWebhooks{due: []*models.Delivery{{
+				ID:       7,
+				URL:      tt.url,
+				Secret:   "s3cr3t",
+				Event:    models.EventSnippetCreated,
+				Payload:  payload,
+				Status:   models.DeliveryPending,
+				Attempts: tt.attempts,
+			}}}
+			app.webhooks = webhooks
+			// The receivers listen on loopback.
+			if !tt.publicOnly {
+				app.webhookClient = newWebhookClient(nil)
+			}
+			received = nil
+
+			assert.NilError(t, app.deliverWebhooks(context.Background()))
+			assert.Equal(t, len(webhooks.recorded), 1)
+			d := webhooks.recorded[0]
+			assert.Equal(t, d.Status, tt.wantStatus)
+			assert.Equal(t, d.Attempts, tt.attempts+1)
+			assert.Equal(t, d.ResponseStatus, tt.wantResponse)
+			assert.StringContains(t, d.Error, tt.wantError)
+			if tt.wantError == "" {
+				assert.Equal(t, d.Error, "")
+			}
+			if tt.wantRetryIn > 0 {
+				assert.Equal(t, d.NextAttempt.Sub(d.LastAttempt), tt.wantRetryIn)
+			}
+			if tt.publicOnly {
+				assert.Equal(t, received == nil, true)
+			}
+			if received != nil {
+				assert.Equal(t, received.Get("Content-Type"), "application/json")
+				assert.Equal(t, received.Get("X-Snippetbox-Event"), "snippet.created")
+				assert.Equal(t, received.Get("X-Snippetbox-Delivery"), "7")
+			}
+		})
+	}
+}
+
+func TestDeliverWebhooksCancelled(t *testing.T) {
+	app := newTestApplication(t)
+	webhooks := &queuedWebhooks{due: []*models.Delivery{{
+		ID:     7,
+		URL:    "http://127.0.0.1:1/hook",
+		Secret: "s3cr3t",
+		Status: models.DeliveryPending,
+	}}}
+	app.webhooks = webhooks
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The interrupted attempt isn't recorded, so the delivery stays due.
+	assert.NilError(t, app.deliverWebhooks(ctx))
+	assert.Equal(t, len(webhooks.recorded), 0)
+}
+
+func TestWebhookCreateFormURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://example.com/hook", true},
+		{"http://93.184.216.34:8080/hook", true},
+		{"http://[2606:2800:220:1:248:1893:25c8:1946]/hook", true},
+		{"http://localhost/hook", false},
+		{"http://LocalHost./hook", false},
+		{"http://app.localhost/hook", false},
+		{"http://127.0.0.1:8080/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://10.0.0.8/hook", false},
+		{"http://172.16.4.1/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://[fd00::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+		{"ftp://example.com/hook", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			form := webhookCreateForm{URL: tt.url, Events: []models.Event{models.EventSnippetCreated}}
+			form.validate()
+			assert.Equal(t, form.Valid(), tt.valid)
+		})
+	}
+}
+
+func TestDialPublicOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"93.184.216.34:443", false},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", false},
+		{"127.0.0.1:80", true},
+		{"[::1]:80", true},
+		{"0.0.0.0:80", true},
+		{"10.1.2.3:80", true},
+		{"169.254.169.254:80", true},
+		{"[fe80::1]:80", true},
+		{"[::ffff:192.168.0.1]:80", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := dialPublicOnly("tcp", tt.address, nil)
+			assert.Equal(t, err != nil, tt.wantErr)
+		})
+	}
+}
+
+func TestAccountWebhooks(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	code, _, body := ts.get(t, "/account/view")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, string(body), "<h2>Webhooks</h2>")
+	assert.StringContains(t, string(body), "<td>https://example.com/hooks/snippets</td>\n<td>snippet.created, snippet.deleted</td>")
+	assert.StringContains(t, string(body), "<form action='/account/webhooks/delete/1' method='POST'>")
+	assert.StringContains(t, string(body), "<td>receiver replied 500 Internal Server Error</td>")
+
+	form := url.Values{}
+	form.Add("csrf_token", csrfToken)
+	form.Add("url", "ftp://example.com/hook")
+	form.Add("events", "snippet.renamed")
+	code, _, body = ts.postForm(t, "/account/webhooks", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, string(body), "This field must be an http or https URL")
+	assert.StringContains(t, string(body), "This field must only contain the listed events")
+
+	form.Set("url", "http://localhost:8080/hook")
+	code, _, body = ts.postForm(t, "/account/webhooks", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, string(body), "This field must not point to a local or private address")
+
+	form.Del("events")
+	form.Set("url", "https://example.org/hook")
+	code, _, body = ts.postForm(t, "/account/webhooks", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, string(body), "Pick at least one event")
+
+	form.Add("events", "snippet.updated")
+	form.Add("events", "snippet.expired")
+	code, headers, body := ts.postForm(t, "/account/webhooks", form)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, headers.Get("Cache-Control"), "no-store")
+	assert.StringContains(t, string(body), "<code>n3wS3cr3t</code>")
+	assert.StringContains(t, string(body), "<td>https://example.org/hook</td>\n<td>snippet.updated, snippet.expired</td>")
+
+	form = url.Values{}
+	form.Add("csrf_token", csrfToken)
+	code, headers, _ = ts.postForm(t, "/account/webhooks/delete/1", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/account/view")
+	code, _, _ = ts.postForm(t, "/account/webhooks/delete/5", form)
+	assert.Equal(t, code, http.StatusNotFound)
+}
@@ -10,4 +10,7 @@ require (
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/justinas/nosurf v1.1.1
 	golang.org/x/crypto v0.0.0-20200317142112-1b76d66859c6
+	golang.org/x/term v0.15.0
 )
+
+require golang.org/x/sys v0.15.0 // indirect
@@ -0,0 +1,321 @@
+// Package cli implements the snippet command, which creates, fetches,
+// lists and deletes snippets on a snippetbox server from the command line.
+// It lives outside cmd/snippet so that the server's tests can run it.
+package cli
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/client"
+	"golang.org/x/term"
+)
+
+const usage = `Usage: snippet [-config file] <command> [arguments]
+
+Commands:
+  login [-server url] [-insecure] [-token token]
+        store the server and an API token; without -token, ask for an
+        email and password and create a token
+  logout
+        forget the stored token
+  create [-title title] [-language lang] [-expires 1d] [-visibility unlisted] [file]
+        create a snippet from a file or standard input and print its URL
+  get <slug>
+        print the content of a snippet
+  list
+        list your snippets
+  delete <slug>...
+        delete snippets
+`
+
+// errUsage reports a command line that doesn't make sense. The usage has
+// already been printed.
+var errUsage = errors.New("usage")
+
+// Run runs the snippet command with the given arguments, not including the
+// program name, and returns its exit code.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("snippet", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	configPath := flags.String("config", "", "config file (default is snippetbox/config.json in the user config directory)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	if *configPath == "" {
+		path, err := defaultConfigPath()
+		if err != nil {
+			fmt.Fprintln(stderr, "snippet:", err)
+			return 1
+		}
+		*configPath = path
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, "snippet:", err)
+		return 1
+	}
+
+	cmd := &command{cfg: cfg, configPath: *configPath, stdin: stdin, stdout: stdout, stderr: stderr}
+	name, args := flags.Arg(0), flags.Args()[1:]
+	switch name {
+	case "login":
+		err = cmd.login(args)
+	case "logout":
+		err = cmd.logout(args)
+	case "create":
+		err = cmd.create(args)
+	case "get":
+		err = cmd.get(args)
+	case "list":
+		err = cmd.list(args)
+	case "delete":
+		err = cmd.delete(args)
+	default:
+		fmt.Fprintf(stderr, "snippet: unknown command %q\n", name)
+		flags.Usage()
+		return 2
+	}
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "snippet %s: %s\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+type command struct {
+	cfg        *config
+	configPath string
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+func (cmd *command) flagSet(name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(cmd.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(cmd.stderr, "Usage: snippet %s %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// client returns an API client for the configured server. Commands that
+// act on the user's snippets need a token.
+func (cmd *command) client(needToken bool) (*client.Client, error) {
+	if cmd.cfg.Server == "" || (needToken && cmd.cfg.Token == "") {
+		return nil, errors.New("not logged in, run snippet login first")
+	}
+	c := &client.Client{BaseURL: cmd.cfg.Server, Token: cmd.cfg.Token, HTTPClient: &http.Client{Timeout: time.Minute}}
+	if cmd.cfg.Insecure {
+		c.HTTPClient.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	return c, nil
+}
+
+func (cmd *command) login(args []string) error {
+	flags := cmd.flagSet("login", "[-server url] [-insecure] [-token token]")
+	server := flags.String("server", "https://localhost:4000", "URL of the snippetbox server")
+	insecure := flags.Bool("insecure", false, "don't verify the server's TLS certificate")
+	token := flags.String("token", "", "API token created on your account page")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return errUsage
+	}
+	cmd.cfg.Server, cmd.cfg.Insecure, cmd.cfg.Token = strings.TrimSuffix(*server, "/"), *insecure, *token
+	if cmd.cfg.Token == "" {
+		c, err := cmd.client(false)
+		if err != nil {
+			return err
+		}
+		in := bufio.NewReader(cmd.stdin)
+		email, err := cmd.prompt(in, "Email: ")
+		if err != nil {
+			return err
+		}
+		password, err := cmd.promptPassword(in, "Password: ")
+		if err != nil {
+			return err
+		}
+		host, _ := os.Hostname()
+		// The token expires after the server's default for API logins.
+		scopes := []string{"read", "write", "delete"}
+		cmd.cfg.Token, err = c.Login(email, password, strings.TrimSpace("snippet CLI "+host), scopes)
+		if err != nil {
+			return err
+		}
+	}
+	if err := cmd.cfg.save(cmd.configPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.stdout, "Logged in to %s\n", cmd.cfg.Server)
+	return nil
+}
+
+func (cmd *command) prompt(in *bufio.Reader, label string) (string, error) {
+	fmt.Fprint(cmd.stderr, label)
+	line, err := in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("reading %s%w", strings.ToLower(label), err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptPassword is prompt without echoing what is typed, when standard
+// input is a terminal.
+func (cmd *command) promptPassword(in *bufio.Reader, label string) (string, error) {
+	f, ok := cmd.stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return cmd.prompt(in, label)
+	}
+	fmt.Fprint(cmd.stderr, label)
+	password, err := term.ReadPassword(int(f.Fd()))
+	// The newline typed after the password isn't echoed either.
+	fmt.Fprintln(cmd.stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading %s%w", strings.ToLower(label), err)
+	}
+	return string(password), nil
+}
+
+func (cmd *command) logout(args []string) error {
+	if len(args) > 0 {
+		fmt.Fprintln(cmd.stderr, "Usage: snippet logout")
+		return errUsage
+	}
+	// The token stays valid until it is revoked on the account page.
+	cmd.cfg.Token = ""
+	return cmd.cfg.save(cmd.configPath)
+}
+
+func (cmd *command) create(args []string) error {
+	flags := cmd.flagSet("create", "[flags] [file]")
+	var opts client.NewSnippet
+	flags.StringVar(&opts.Title, "title", "", "title of the snippet (default is the file name)")
+	flags.StringVar(&opts.Language, "language", "", `language to highlight the snippet as, or "text" (default is to detect it)`)
+	flags.StringVar(&opts.Expires, "expires", "", `how long to keep the snippet, such as 30m, 12h or 7d, or "never" (default 7d)`)
+	flags.StringVar(&opts.Visibility, "visibility", "", "public, unlisted or private (default unlisted)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return errUsage
+	}
+	c, err := cmd.client(false)
+	if err != nil {
+		return err
+	}
+	content := cmd.stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		content = f
+		if opts.Title == "" {
+			opts.Title = filepath.Base(path)
+		}
+	}
+	url, err := c.Create(content, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.stdout, url)
+	return nil
+}
+
+func (cmd *command) get(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.stderr, "Usage: snippet get <slug>")
+		return errUsage
+	}
+	c, err := cmd.client(false)
+	if err != nil {
+		return err
+	}
+	snippet, err := c.Get(slugOf(args[0]))
+	if err != nil {
+		return err
+	}
+	// Only the browser that has the key in the link can decrypt it.
+	if snippet.Encrypted {
+		return fmt.Errorf("snippet %s is encrypted, open its link in a browser", snippet.Slug)
+	}
+	_, err = io.WriteString(cmd.stdout, snippet.Content)
+	return err
+}
+
+func (cmd *command) list(args []string) error {
+	if len(args) > 0 {
+		fmt.Fprintln(cmd.stderr, "Usage: snippet list")
+		return errUsage
+	}
+	c, err := cmd.client(true)
+	if err != nil {
+		return err
+	}
+	snippets, err := c.List()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(cmd.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SLUG\tVISIBILITY\tEXPIRES\tTITLE")
+	for _, s := range snippets {
+		expires := "never"
+		if !s.Expires.IsZero() {
+			expires = s.Expires.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Slug, s.Visibility, expires, s.Title)
+	}
+	return w.Flush()
+}
+
+func (cmd *command) delete(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(cmd.stderr, "Usage: snippet delete <slug>...")
+		return errUsage
+	}
+	c, err := cmd.client(true)
+	if err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if err := c.Delete(slugOf(arg)); err != nil {
+			return fmt.Errorf("%s: %w", arg, err)
+		}
+	}
+	return nil
+}
+
+// slugOf accepts a snippet's URL as well as its slug.
+func slugOf(s string) string {
+	if i := strings.IndexAny(s, "?#"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSuffix(s, "/")
+	return s[strings.LastIndexByte(s, '/')+1:]
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// config is what the snippet command remembers between runs. It holds an
+// API token, so it is only readable by its owner.
+type config struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+	// Insecure skips the verification of the server's TLS certificate, for
+	// development servers with self-signed certificates.
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// defaultConfigPath returns the config file in the user's config directory,
+// such as ~/.config/snippetbox/config.json on Linux.
+func defaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snippetbox", "config.json"), nil
+}
+
+// loadConfig reads the config file. A missing file gives an empty config.
+func loadConfig(path string) (*config, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg := &config{}
+	if err = json.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *config) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	// WriteFile only applies the mode to new files.
+	if err = os.WriteFile(path, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0o600)
+}
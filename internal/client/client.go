@@ -0,0 +1,207 @@
+// Package client talks to the HTTP API of a snippetbox server. It is used by
+// the snippet command.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Client sends requests to the server at BaseURL, authenticated with Token
+// when it is set.
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// Error is an error response from the server. Fields holds the errors of
+// individual fields when a request failed validation.
+type Error struct {
+	StatusCode int
+	Message    string
+	Fields     map[string]string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("server replied %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg = fmt.Sprintf("server replied %d: %s", e.StatusCode, e.Message)
+	}
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		msg += fmt.Sprintf("\n%s: %s", field, e.Fields[field])
+	}
+	return msg
+}
+
+// Snippet is a snippet as listed by the API. Content is only set by Get,
+// and Expires is the zero time for snippets that never expire.
+type Snippet struct {
+	Slug       string    `json:"slug"`
+	URL        string    `json:"url"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Language   string    `json:"language"`
+	Visibility string    `json:"visibility"`
+	Encrypted  bool      `json:"encrypted"`
+	Created    time.Time `json:"created"`
+	Expires    time.Time `json:"expires"`
+}
+
+// NewSnippet holds the options of a snippet to create. Empty fields take the
+// server's defaults.
+type NewSnippet struct {
+	Title    string
+	Language string
+	// Expires is a number followed by m, h or d, or "never".
+	Expires    string
+	Visibility string
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// do sends a request and returns the body of a successful response. Error
+// responses are returned as *Error.
+func (c *Client) do(method, path string, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	// Redirects only lead to web pages, which are no use here.
+	hc := *c.httpClient()
+	hc.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	rs, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Body.Close()
+	b, err := io.ReadAll(rs.Body)
+	if err != nil {
+		return nil, err
+	}
+	if rs.StatusCode >= 300 {
+		e := &Error{StatusCode: rs.StatusCode}
+		// The API replies with JSON errors, other endpoints with plain text.
+		var js struct {
+			Error struct {
+				Message string            `json:"message"`
+				Fields  map[string]string `json:"fields"`
+			} `json:"error"`
+		}
+		switch contentType := rs.Header.Get("Content-Type"); {
+		case strings.HasPrefix(contentType, "application/json"):
+			if json.Unmarshal(b, &js) == nil {
+				e.Message, e.Fields = js.Error.Message, js.Error.Fields
+			}
+		case strings.HasPrefix(contentType, "text/plain"):
+			e.Message = strings.TrimSpace(string(b))
+		}
+		return nil, e
+	}
+	return b, nil
+}
+
+// Login exchanges an email and password for a new API token with the given
+// scopes, which is listed under name on the user's account page.
+func (c *Client) Login(email, password, name string, scopes []string) (string, error) {
+	req, err := json.Marshal(map[string]any{"email": email, "password": password, "name": name, "scopes": scopes})
+	if err != nil {
+		return "", err
+	}
+	b, err := c.do(http.MethodPost, "/api/v1/tokens", "application/json", bytes.NewReader(req))
+	if err != nil {
+		return "", err
+	}
+	var rs struct {
+		Token string `json:"token"`
+	}
+	if err = json.Unmarshal(b, &rs); err != nil {
+		return "", err
+	}
+	return rs.Token, nil
+}
+
+// Create pastes content as a new snippet and returns its URL.
+func (c *Client) Create(content io.Reader, opts NewSnippet) (string, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"title":      opts.Title,
+		"language":   opts.Language,
+		"expires":    opts.Expires,
+		"visibility": opts.Visibility,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	path := "/paste"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	b, err := c.do(http.MethodPost, path, "text/plain; charset=utf-8", content)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Get returns the snippet with the given slug, with its content.
+func (c *Client) Get(slug string) (*Snippet, error) {
+	b, err := c.do(http.MethodGet, "/api/v1/snippets/"+url.PathEscape(slug), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	var rs struct {
+		Snippet *Snippet `json:"snippet"`
+	}
+	if err = json.Unmarshal(b, &rs); err != nil {
+		return nil, err
+	}
+	return rs.Snippet, nil
+}
+
+// List returns the snippets of the token's user, newest first.
+func (c *Client) List() ([]*Snippet, error) {
+	b, err := c.do(http.MethodGet, "/api/v1/user/snippets", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	var rs struct {
+		Snippets []*Snippet `json:"snippets"`
+	}
+	if err = json.Unmarshal(b, &rs); err != nil {
+		return nil, err
+	}
+	return rs.Snippets, nil
+}
+
+// Delete deletes a snippet of the token's user.
+func (c *Client) Delete(slug string) error {
+	_, err := c.do(http.MethodDelete, "/api/v1/snippets/"+url.PathEscape(slug), "", nil)
+	return err
+}
@@ -0,0 +1,213 @@
+// Package detect guesses the language of a snippet from its content. The
+// names of the languages are the ones the syntax package highlights.
+package detect
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Result is a guessed language with a confidence between 0 and 1. The zero
+// Result means the content looks like plain text.
+type Result struct {
+	Language   string
+	Confidence float64
+}
+
+// minScore is the least evidence needed before any language is guessed.
+const minScore = 4
+
+// interpreters maps the interpreters of shebang lines to languages.
+var interpreters = map[string]string{
+	"sh":      "shell",
+	"bash":    "shell",
+	"zsh":     "shell",
+	"dash":    "shell",
+	"ksh":     "shell",
+	"python":  "python",
+	"python2": "python",
+	"python3": "python",
+	"node":    "javascript",
+	"nodejs":  "javascript",
+	"deno":    "javascript",
+}
+
+// pattern is a telltale sign of a language. Each match adds weight to the
+// language's score, up to three matches.
+type pattern struct {
+	rx     *regexp.Regexp
+	weight float64
+}
+
+type language struct {
+	name     string
+	patterns []pattern
+	// keywords are words that are common in the language and rare in prose.
+	keywords map[string]bool
+	foldCase bool
+}
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var languages = []*language{
+	{
+		name: "go",
+		patterns: []pattern{
+			{regexp.MustCompile(`(?m)^package \w+\s*$`), 6},
+			{regexp.MustCompile(`(?m)^import (\(|"[\w./-]+")`), 4},
+			{regexp.MustCompile(`(?m)^func (\(\w+ \*?\w+\) )?\w+\(`), 4},
+			{regexp.MustCompile(`\w+ := `), 2},
+			{regexp.MustCompile(`\berr != nil\b`), 4},
+			{regexp.MustCompile(`\bfmt\.\w+\(`), 3},
+			{regexp.MustCompile(`\bchan\b|<-`), 2},
+		},
+		keywords: words(`func package chan defer go struct interface nil range fallthrough goto select map var const`),
+	},
+	{
+		name: "python",
+		patterns: []pattern{
+			{regexp.MustCompile(`(?m)^\s*def \w+\(.*\)\s*(->\s*[\w\[\], .]+)?:\s*$`), 5},
+			{regexp.MustCompile(`(?m)^\s*class \w+(\(.*\))?:\s*$`), 5},
+			{regexp.MustCompile(`(?m)^from [\w.]+ import `), 5},
+			{regexp.MustCompile(`(?m)^import [\w.]+(, [\w.]+)*\s*$`), 3},
+			{regexp.MustCompile(`(?m)^\s*(if|elif|else|for|while|try|except|with)\b.*:\s*$`), 2},
+			{regexp.MustCompile(`\bself\.\w+`), 2},
+			{regexp.MustCompile(`__\w+__`), 3},
+			{regexp.MustCompile(`\bprint\(`), 1},
+		},
+		keywords: words(`def elif self None True False lambda pass yield except raise nonlocal async await`),
+	},
+	{
+		name: "javascript",
+		patterns: []pattern{
+			{regexp.MustCompile(`\b(const|let|var) \w+ = `), 3},
+			{regexp.MustCompile(`\bfunction\s*\w*\s*\(`), 3},
+			{regexp.MustCompile(`=>`), 2},
+			{regexp.MustCompile(`===|!==`), 3},
+			{regexp.MustCompile(`\bconsole\.\w+\(`), 4},
+			{regexp.MustCompile(`\b(document|window)\.\w+`), 3},
+			{regexp.MustCompile(`\brequire\(['"]`), 4},
+			{regexp.MustCompile(`(?m)^(import .* from |export (default )?)`), 4},
+			{regexp.MustCompile(`;\s*$`), 1},
+		},
+		keywords: words(`const let var function undefined null this typeof instanceof new return async await`),
+	},
+	{
+		name: "sql",
+		patterns: []pattern{
+			{regexp.MustCompile(`(?is)\bselect\b.+?\bfrom\b`), 5},
+			{regexp.MustCompile(`(?i)\binsert\s+into\b`), 5},
+			{regexp.MustCompile(`(?i)\bupdate\s+\w+\s+set\b`), 5},
+			{regexp.MustCompile(`(?i)\bdelete\s+from\b`), 5},
+			{regexp.MustCompile(`(?i)\b(create|alter|drop)\s+(table|index|view|database)\b`), 6},
+			{regexp.MustCompile(`(?i)\b(inner|left|right|outer)?\s*join\b.+\bon\b`), 2},
+			{regexp.MustCompile(`(?i)\b(where|group by|order by|having)\b`), 1},
+		},
+		keywords: words(`select from where join insert into update delete values table group order having limit`),
+		foldCase: true,
+	},
+	{
+		name: "shell",
+		patterns: []pattern{
+			{regexp.MustCompile(`(?m)^\s*\$ \w`), 4},
+			{regexp.MustCompile(`(?m)^\s*(fi|done|esac)\s*$`), 4},
+			{regexp.MustCompile(`(?m)^\s*(if \[|while \[|for \w+ in )`), 4},
+			{regexp.MustCompile(`\$\{?\w+\}?`), 1},
+			{regexp.MustCompile(`(?m)^\s*(echo|export|sudo|apt-get|apt|brew|curl|wget|chmod|mkdir|cd|git|docker|make) `), 2},
+			{regexp.MustCompile(`\s-{1,2}[a-z][\w-]*`), 1},
+			{regexp.MustCompile(`\s(\||&&|>>?)\s`), 1},
+		},
+		keywords: words(`echo fi then elif esac done export sudo grep local`),
+	},
+}
+
+// Detect guesses the language of content. A shebang line settles it;
+// otherwise every language scores points for telltale patterns and for how
+// many of the words are its keywords, and the confidence is the winner's
+// share of all points.
+func Detect(content string) Result {
+	if lang, ok := shebang(content); ok {
+		return Result{Language: lang, Confidence: 1}
+	}
+
+	scores := make(map[string]float64, len(languages))
+	contentWords := tokens(content)
+	var total float64
+	for _, l := range languages {
+		var score float64
+		for _, p := range l.patterns {
+			n := len(p.rx.FindAllStringIndex(content, 3))
+			score += float64(n) * p.weight
+		}
+		if len(contentWords) > 0 {
+			hits := 0
+			for _, w := range contentWords {
+				if l.foldCase {
+					w = strings.ToLower(w)
+				}
+				if l.keywords[w] {
+					hits++
+				}
+			}
+			// The share of keywords matters more than their number, so that
+			// long texts don't win on volume alone.
+			score += 10 * float64(hits) / float64(len(contentWords))
+		}
+		scores[l.name] = score
+		total += score
+	}
+
+	var best Result
+	var bestScore float64
+	for _, l := range languages {
+		if scores[l.name] > bestScore {
+			best.Language, bestScore = l.name, scores[l.name]
+		}
+	}
+	if bestScore < minScore {
+		return Result{}
+	}
+	best.Confidence = math.Round(bestScore/total*100) / 100
+	return best
+}
+
+// shebang returns the language of the interpreter named on a #! line.
+func shebang(content string) (string, bool) {
+	if !strings.HasPrefix(content, "#!") {
+		return "", false
+	}
+	line := content[2:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", false
+	}
+	// #!/usr/bin/env python3 names the interpreter after env.
+	interpreter := fields[0][strings.LastIndexByte(fields[0], '/')+1:]
+	if interpreter == "env" {
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interpreter = f
+				break
+			}
+		}
+	}
+	lang, ok := interpreters[interpreter]
+	return lang, ok
+}
+
+func tokens(content string) []string {
+	return strings.FieldsFunc(content, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
@@ -0,0 +1,144 @@
+package detect
+
+import (
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantLang string
+		// wantSure is whether the guess should be more likely right than
+		// not.
+		wantSure bool
+	}{
+		{
+			name:     "Shebang",
+			content:  "#!/bin/bash\nls",
+			wantLang: "shell",
+			wantSure: true,
+		},
+		{
+			name:     "Shebang through env",
+			content:  "#!/usr/bin/env -S python3 -u\nprint('hi')",
+			wantLang: "python",
+			wantSure: true,
+		},
+		{
+			name:     "Unknown interpreter",
+			content:  "#!/usr/bin/perl\nprint \"hi\";",
+			wantLang: "",
+		},
+		{
+			name: "Go",
+			content: `package main
+
+import "fmt"
+
+func main() {
+	msg, err := greet("world")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(msg)
+}`,
+			wantLang: "go",
+			wantSure: true,
+		},
+		{
+			name: "Go fragment",
+			content: `func (app *application) home(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.Latest()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+}`,
+			wantLang: "go",
+			wantSure: true,
+		},
+		{
+			name: "Python",
+			content: `from collections import Counter
+
+class Words:
+    def __init__(self, text):
+        self.counts = Counter(text.split())
+
+    def top(self, n=3):
+        return self.counts.most_common(n)`,
+			wantLang: "python",
+			wantSure: true,
+		},
+		{
+			name: "JavaScript",
+			content: `const form = document.querySelector('form');
+form.addEventListener('submit', (e) => {
+  if (form.elements.title.value === '') {
+    e.preventDefault();
+    console.log('blank title');
+  }
+});`,
+			wantLang: "javascript",
+			wantSure: true,
+		},
+		{
+			name: "SQL",
+			content: `SELECT s.title, COUNT(*) AS uses
+FROM snippets s
+INNER JOIN snippet_tags st ON st.snippet_id = s.id
+WHERE s.visibility = 'public'
+GROUP BY s.title
+ORDER BY uses DESC;`,
+			wantLang: "sql",
+			wantSure: true,
+		},
+		{
+			name:     "Lowercase SQL",
+			content:  "create table users (id integer primary key, name text);\ninsert into users values (1, 'alice');",
+			wantLang: "sql",
+			wantSure: true,
+		},
+		{
+			name: "Shell",
+			content: `for f in *.log; do
+  if [ -s "$f" ]; then
+    gzip "$f"
+  fi
+done`,
+			wantLang: "shell",
+			wantSure: true,
+		},
+		{
+			name:     "Commands",
+			content:  "$ go mod tidy\n$ go run ./cmd/web -addr=:4000",
+			wantLang: "shell",
+			wantSure: true,
+		},
+		{
+			name:     "Prose",
+			content:  "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.",
+			wantLang: "",
+		},
+		{
+			name:     "Empty",
+			content:  "",
+			wantLang: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.content)
+			assert.Equal(t, got.Language, tt.wantLang)
+			if tt.wantLang == "" {
+				assert.Equal(t, got.Confidence, 0.0)
+				return
+			}
+			assert.Equal(t, got.Confidence > 0 && got.Confidence <= 1, true)
+			assert.Equal(t, got.Confidence > 0.5, tt.wantSure)
+		})
+	}
+}
@@ -0,0 +1,294 @@
+// Package diff computes line-oriented differences between two texts using
+// Myers' O(ND) algorithm and groups them into unified diff hunks.
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxEdits bounds the number of inserted and deleted lines the search for
+// the shortest edit script goes through. It keeps the time and memory a diff
+// takes in check: the search remembers O(MaxEdits²) positions, whatever the
+// size of the texts.
+const MaxEdits = 1000
+
+// ErrTooDifferent is returned for texts that take more than MaxEdits
+// inserted and deleted lines to turn into each other, apart from the lines
+// they start and end with in common.
+var ErrTooDifferent = errors.New("diff: the texts differ too much")
+
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+func (op Op) String() string {
+	switch op {
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	default:
+		return "equal"
+	}
+}
+
+// Prefix returns the marker used for the operation in unified diff output.
+func (op Op) Prefix() string {
+	switch op {
+	case Insert:
+		return "+"
+	case Delete:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+// Line is a single line of an edit script. OldNumber and NewNumber are the
+// 1-based line numbers in the old and new text, and are zero when the line
+// doesn't exist on that side.
+type Line struct {
+	Op        Op
+	Text      string
+	OldNumber int
+	NewNumber int
+}
+
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+// Header returns the "@@ -a,b +c,d @@" range line for the hunk.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// Lines returns the full edit script that turns a into b, or
+// ErrTooDifferent.
+func Lines(a, b string) ([]Line, error) {
+	return script(split(a), split(b))
+}
+
+// Unified returns the hunks of a unified diff between a and b, with the
+// given number of unchanged context lines around every change. It returns
+// nil when the texts are equal line by line, and ErrTooDifferent when they
+// differ too much to diff.
+func Unified(a, b string, context int) ([]Hunk, error) {
+	lines, err := Lines(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	var hunks []Hunk
+	oldSeen, newSeen := 0, 0
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			oldSeen++
+			newSeen++
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// Extend the hunk while the next change is close enough that the
+		// context windows would overlap.
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].Op != Equal {
+				end = j
+				continue
+			}
+			if j-end > 2*context {
+				break
+			}
+		}
+		stop := end + context + 1
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		h := Hunk{Lines: lines[start:stop]}
+		// Context lines before the first change have already been counted.
+		oldSeen -= i - start
+		newSeen -= i - start
+		h.OldStart, h.NewStart = oldSeen, newSeen
+		for _, l := range h.Lines {
+			if l.Op != Insert {
+				h.OldLines++
+			}
+			if l.Op != Delete {
+				h.NewLines++
+			}
+		}
+		if h.OldLines > 0 {
+			h.OldStart++
+		}
+		if h.NewLines > 0 {
+			h.NewStart++
+		}
+		oldSeen += h.OldLines
+		newSeen += h.NewLines
+		hunks = append(hunks, h)
+		i = stop
+	}
+	return hunks, nil
+}
+
+// Format renders hunks in the unified diff format understood by patch(1).
+func Format(oldName, newName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		sb.WriteString(h.Header())
+		sb.WriteByte('\n')
+		for _, l := range h.Lines {
+			sb.WriteString(l.Op.Prefix())
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// split breaks s into lines, treating CRLF (which browsers submit for
+// textareas) the same as LF and ignoring a trailing newline.
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	return strings.Split(s, "\n")
+}
+
+// script finds the shortest edit script between a and b. The lines they
+// start and end with in common are set aside first, so that small changes
+// to large texts stay well within MaxEdits.
+func script(a, b []string) ([]Line, error) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	middle, err := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]Line, 0, prefix+len(middle)+suffix)
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{Op: Equal, Text: a[i], OldNumber: i + 1, NewNumber: i + 1})
+	}
+	for _, l := range middle {
+		if l.OldNumber > 0 {
+			l.OldNumber += prefix
+		}
+		if l.NewNumber > 0 {
+			l.NewNumber += prefix
+		}
+		lines = append(lines, l)
+	}
+	for i := 0; i < suffix; i++ {
+		x, y := len(a)-suffix+i, len(b)-suffix+i
+		lines = append(lines, Line{Op: Equal, Text: a[x], OldNumber: x + 1, NewNumber: y + 1})
+	}
+	return lines, nil
+}
+
+// myers runs Myers' algorithm on a and b. It records the furthest reaching
+// D-paths for every D and then walks them backwards from the end of both
+// inputs. Only the diagonals a D-path can reach, -D-1 to D+1, are recorded
+// for each D.
+func myers(a, b []string) ([]Line, error) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > MaxEdits {
+		max = MaxEdits
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	found := false
+search:
+	for d := 0; d <= max; d++ {
+		// The snapshot holds diagonals -d-1 to d+1, so diagonal k is at
+		// index k+d+1.
+		snapshot := make([]int, 2*d+3)
+		copy(snapshot, v[offset-d-1:offset+d+2])
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break search
+			}
+		}
+	}
+	if !found {
+		return nil, ErrTooDifferent
+	}
+
+	var reversed []Line
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[k-1+d+1] < v[k+1+d+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Op: Equal, Text: a[x-1], OldNumber: x, NewNumber: y})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Line{Op: Insert, Text: b[y-1], NewNumber: y})
+			} else {
+				reversed = append(reversed, Line{Op: Delete, Text: a[x-1], OldNumber: x})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	lines := make([]Line, len(reversed))
+	for i := range reversed {
+		lines[i] = reversed[len(reversed)-1-i]
+	}
+	return lines, nil
+}
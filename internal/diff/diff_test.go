@@ -0,0 +1,137 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "Equal",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: "",
+		},
+		{
+			name: "Empty to text",
+			a:    "",
+			b:    "one\ntwo",
+			want: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+one\n+two\n",
+		},
+		{
+			name: "Text to empty",
+			a:    "one\ntwo",
+			b:    "",
+			want: "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-one\n-two\n",
+		},
+		{
+			name: "Changed line",
+			a:    "one\ntwo\nthree",
+			b:    "one\n2\nthree",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			name: "CRLF is ignored",
+			a:    "one\r\ntwo\r\n",
+			b:    "one\ntwo",
+			want: "",
+		},
+		{
+			name: "Separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12",
+			b:    "x\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ny",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+y\n",
+		},
+		{
+			name: "Nearby changes share a hunk",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8",
+			b:    "x\n2\n3\n4\n5\n6\n7\ny",
+			want: "--- a\n+++ b\n@@ -1,8 +1,8 @@\n-1\n+x\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+y\n",
+		},
+		{
+			name: "Insertion in the middle",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9",
+			b:    "1\n2\n3\n4\n5\nnew\n6\n7\n8\n9",
+			want: "--- a\n+++ b\n@@ -3,6 +3,7 @@\n 3\n 4\n 5\n+new\n 6\n 7\n 8\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks, err := Unified(tt.a, tt.b, 3)
+			assert.NilError(t, err)
+			assert.Equal(t, Format("a", "b", hunks), tt.want)
+		})
+	}
+}
+
+func TestLinesNumbers(t *testing.T) {
+	lines, err := Lines("a\nb\nc", "a\nc\nd")
+	assert.NilError(t, err)
+	want := []Line{
+		{Op: Equal, Text: "a", OldNumber: 1, NewNumber: 1},
+		{Op: Delete, Text: "b", OldNumber: 2},
+		{Op: Equal, Text: "c", OldNumber: 3, NewNumber: 2},
+		{Op: Insert, Text: "d", NewNumber: 3},
+	}
+	assert.Equal(t, len(lines), len(want))
+	for i := range want {
+		assert.Equal(t, lines[i], want[i])
+	}
+}
+
+// numbered returns n lines that start with prefix.
+func numbered(prefix string, n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "%s%d\n", prefix, i)
+	}
+	return sb.String()
+}
+
+func TestUnifiedLargeTexts(t *testing.T) {
+	// A small change deep inside large texts is found despite MaxEdits.
+	a := numbered("line ", 10000)
+	b := strings.Replace(a, "line 5000\n", "changed\n", 1)
+	hunks, err := Unified(a, b, 3)
+	assert.NilError(t, err)
+	assert.Equal(t, len(hunks), 1)
+	assert.Equal(t, hunks[0].Header(), "@@ -4998,7 +4998,7 @@")
+
+	// Texts with nothing in common take more than MaxEdits edits.
+	_, err = Unified(numbered("a", MaxEdits/2), numbered("b", MaxEdits/2+1), 3)
+	assert.Equal(t, errors.Is(err, ErrTooDifferent), true)
+	hunks, err = Unified(numbered("a", MaxEdits/2), numbered("b", MaxEdits/2), 3)
+	assert.NilError(t, err)
+	assert.Equal(t, len(hunks), 1)
+}
+
+// TestUnifiedMemory bounds the memory diffing fully different texts takes,
+// which used to grow with the product of their length and the number of
+// edits: gigabytes for snippets of a few thousand lines.
+func TestUnifiedMemory(t *testing.T) {
+	for _, lines := range []int{4000, 32000} {
+		t.Run(fmt.Sprint(lines), func(t *testing.T) {
+			a, b := numbered("a", lines), numbered("b", lines)
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err := Unified(a, b, 3)
+			runtime.ReadMemStats(&after)
+			assert.Equal(t, errors.Is(err, ErrTooDifferent), true)
+			// The texts are split into lines, the rest is the search.
+			if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 32<<20 {
+				t.Errorf("diffing %d lines allocated %d MiB", lines, alloc>>20)
+			}
+		})
+	}
+}
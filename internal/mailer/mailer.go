@@ -0,0 +1,98 @@
+// Package mailer sends the emails the site needs, such as links to verify an
+// address. Mail goes through the Mailer interface so that a real transport
+// can be plugged in; Outbox keeps messages in memory and, for development,
+// writes each one to a file.
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Outbox is a Mailer that delivers nothing. It keeps every message it is
+// given and, when Dir is set, also writes each to a file there that can be
+// opened with a mail client. It is safe for concurrent use.
+type Outbox struct {
+	Dir string
+
+	mu       sync.Mutex
+	messages []Message
+	now      func() time.Time
+}
+
+// Send stores msg in the outbox.
+func (o *Outbox) Send(msg Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.Dir != "" {
+		if err := o.write(msg); err != nil {
+			return err
+		}
+	}
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message(nil), o.messages...)
+}
+
+// Last returns the latest message sent to an address, and whether there was
+// any.
+func (o *Outbox) Last(to string) (Message, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.messages) - 1; i >= 0; i-- {
+		if o.messages[i].To == to {
+			return o.messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// write saves msg to a .eml file named after when it was sent and the
+// recipient.
+func (o *Outbox) write(msg Message) error {
+	now := time.Now
+	if o.now != nil {
+		now = o.now
+	}
+	t := now().UTC()
+	if err := os.MkdirAll(o.Dir, 0o700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d-%s.eml", t.Format("20060102T150405"), len(o.messages)+1, safeName(msg.To))
+	content := fmt.Sprintf("Date: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		t.Format(time.RFC1123Z), msg.To, msg.Subject, strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return os.WriteFile(filepath.Join(o.Dir, name), []byte(content), 0o600)
+}
+
+// safeName keeps the characters of an address that are safe in file names.
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '@', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestOutbox(t *testing.T) {
+	dir := t.TempDir()
+	o := &Outbox{
+		Dir: dir,
+		now: func() time.Time { return time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC) },
+	}
+	assert.NilError(t, o.Send(Message{To: "alice@example.com", Subject: "First", Body: "one"}))
+	assert.NilError(t, o.Send(Message{To: "bob@example.com", Subject: "Hello", Body: "two"}))
+	assert.NilError(t, o.Send(Message{To: "../alice@example.com", Subject: "Second", Body: "line 1\nline 2"}))
+
+	assert.Equal(t, len(o.Messages()), 3)
+	msg, ok := o.Last("bob@example.com")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, msg.Subject, "Hello")
+	_, ok = o.Last("carol@example.com")
+	assert.Equal(t, ok, false)
+
+	b, err := os.ReadFile(filepath.Join(dir, "20261018T093000-3-.._alice@example.com.eml"))
+	assert.NilError(t, err)
+	assert.StringContains(t, string(b), "To: ../alice@example.com\r\nSubject: Second\r\n")
+	assert.StringContains(t, string(b), "\r\n\r\nline 1\r\nline 2")
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 3)
+}
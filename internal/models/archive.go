@@ -0,0 +1,106 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SnippetOrder is an order the archive of listed snippets can be browsed in.
+type SnippetOrder string
+
+const (
+	OrderNewest SnippetOrder = "newest"
+	OrderOldest SnippetOrder = "oldest"
+	// OrderExpiring lists snippets that expire, soonest first. Snippets that
+	// never expire are left out.
+	OrderExpiring SnippetOrder = "expiring"
+)
+
+// Cursor is a position in the archive, given by the sort key and ID of a
+// snippet. The zero Cursor is the start of the archive.
+type Cursor struct {
+	Time time.Time
+	ID   int
+	// Before selects the snippets preceding the position instead of the
+	// ones following it.
+	Before bool
+}
+
+func (c Cursor) IsZero() bool {
+	return c.ID == 0
+}
+
+// Cursor returns the position of s in the archive browsed in order o.
+func (o SnippetOrder) Cursor(s *Snippet) Cursor {
+	if o == OrderExpiring {
+		return Cursor{Time: s.Expires, ID: s.ID}
+	}
+	return Cursor{Time: s.Created, ID: s.ID}
+}
+
+// Archive returns at most limit listed snippets next to cursor in the given
+// order. Pages are found by comparing (created, id) or (expires, id) with
+// the cursor rather than with an OFFSET, so that deep pages are as cheap as
+// the first one: InnoDB secondary indexes end with the primary key, which
+// makes idx_snippets_created and idx_snippets_expires indexes on exactly
+// those pairs. Snippets are returned in order even when cursor.Before is
+// set.
+func (m *SnippetModel) Archive(order SnippetOrder, cursor Cursor, limit int) ([]*Snippet, error) {
+	var column, where string
+	ascending := true
+	switch order {
+	case OrderNewest:
+		column, ascending = "s.created", false
+	case OrderOldest:
+		column = "s.created"
+	case OrderExpiring:
+		column, where = "s.expires", " AND s.expires IS NOT NULL"
+	default:
+		return nil, fmt.Errorf("models: unknown snippet order %q", order)
+	}
+	// Pages before the cursor are read backwards from it and reversed.
+	if cursor.Before {
+		ascending = !ascending
+	}
+	cmp, dir := ">", "ASC"
+	if !ascending {
+		cmp, dir = "<", "DESC"
+	}
+
+	args := []any{}
+	if !cursor.IsZero() {
+		where += fmt.Sprintf(" AND (%s, s.id) %s (?, ?)", column, cmp)
+		args = append(args, cursor.Time, cursor.ID)
+	}
+	args = append(args, limit)
+	stmt := `SELECT s.id, s.slug, COALESCE(s.user_id, 0), s.title, s.content, s.visibility, s.burn_after_reading, s.encrypted, s.created, s.expires
+	FROM snippets s
+	WHERE ` + listedSnippets + where + `
+	ORDER BY ` + column + ` ` + dir + `, s.id ` + dir + `
+	LIMIT ?`
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s := &Snippet{}
+		var expires sql.NullTime
+		if err := rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Title, &s.Content, &s.Visibility, &s.BurnAfterReading, &s.Encrypted, &s.Created, &expires); err != nil {
+			return nil, err
+		}
+		s.Expires = expires.Time
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if cursor.Before {
+		for i, j := 0, len(snippets)-1; i < j; i, j = i+1, j-1 {
+			snippets[i], snippets[j] = snippets[j], snippets[i]
+		}
+	}
+	return snippets, nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import "encoding/base64"
+
+const (
+	// ciphertextIVSize and ciphertextTagSize are the nonce and authentication
+	// tag sizes of AES-GCM as used by the browser (see ui/static/js/main.js).
+	ciphertextIVSize  = 12
+	ciphertextTagSize = 16
+)
+
+// IsCiphertext reports whether s looks like the content of an encrypted
+// snippet: the unpadded base64url encoding of an AES-GCM nonce followed by
+// the ciphertext and its tag. The server can't decrypt it, so this only
+// rules out payloads that were obviously never encrypted.
+func IsCiphertext(s string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return false
+	}
+	// Encrypted snippets can't be blank either, so there is at least one
+	// byte of ciphertext.
+	return len(b) > ciphertextIVSize+ciphertextTagSize
+}
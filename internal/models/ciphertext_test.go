@@ -0,0 +1,27 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestIsCiphertext(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{name: "Ciphertext", content: "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJw", want: true},
+		{name: "URL-safe alphabet", content: "yMnKy8zNzs_Q0dLT1NXW19jZ2tvc3d7f4OHi4-Tl5ufo6err7O3u7w", want: true},
+		{name: "Plaintext", content: "An old silent pond...", want: false},
+		{name: "Padded", content: "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJw==", want: false},
+		{name: "Only nonce and tag", content: "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGw", want: false},
+		{name: "Empty", content: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, IsCiphertext(tt.content), tt.want)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/models"
+)
+
+// mockResetToken resets the password of the mock user.
+const mockResetToken = "r3s3tT0k3n"
+
+type PasswordResetModel struct{}
+
+func (m *PasswordResetModel) New(email string, ttl time.Duration) (*models.User, string, error) {
+	if email == mockUser.Email {
+		return mockUser, mockResetToken, nil
+	}
+	return nil, "", models.ErrNoRecord
+}
+func (m *PasswordResetModel) Check(token string) (int, error) {
+	if token == mockResetToken {
+		return mockUser.ID, nil
+	}
+	return 0, models.ErrInvalidCredentials
+}
+func (m *PasswordResetModel) Reset(token, password string) (int, error) {
+	if token == mockResetToken {
+		return mockUser.ID, nil
+	}
+	return 0, models.ErrInvalidCredentials
+}
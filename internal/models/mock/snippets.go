@@ -1,32 +1,361 @@
 package mock
 
 import (
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/xyedo/snippetbox/internal/models"
 )
 
 var mockSnippet = &models.Snippet{
-	ID:      1,
-	Title:   "An old silent pond",
-	Content: "An old silent pond...",
-	Created: time.Now(),
-	Expires: time.Now(),
+	ID:         1,
+	Slug:       "aB3dE5gH7j",
+	UserID:     1,
+	Author:     "Alice",
+	Title:      "An old silent pond",
+	Content:    "An old silent pond...",
+	Visibility: models.VisibilityPublic,
+	Tags:       []string{"haiku", "poetry"},
+	Created:    time.Now(),
+	Expires:    time.Now(),
+}
+
+// mockOtherSnippet belongs to a user other than the mock user, which lets
+// tests exercise the ownership checks. It never expires, and is highlighted
+// as Python, where "in" is a keyword.
+var mockOtherSnippet = &models.Snippet{
+	ID:         3,
+	Slug:       "Qw3rTy7uIo",
+	UserID:     2,
+	Author:     "Bob",
+	Title:      "Over the wintry forest",
+	Content:    "Over the wintry forest, winds howl in rage...",
+	Language:   "python",
+	Visibility: models.VisibilityPublic,
+	Tags:       []string{"poetry"},
+	Created:    time.Now(),
+}
+
+var mockPrivateSnippet = &models.Snippet{
+	ID:         4,
+	Slug:       "Zx9Wv8Ut7s",
+	UserID:     1,
+	Author:     "Alice",
+	Title:      "Dear diary",
+	Content:    "Today I wrote a private snippet",
+	Visibility: models.VisibilityPrivate,
+	Tags:       []string{"poetry"},
+	Created:    time.Now(),
+	Expires:    time.Now(),
+}
+
+var mockBurnSnippet = &models.Snippet{
+	ID:               5,
+	Slug:             "Bu7nAfT3rR",
+	UserID:           2,
+	Author:           "Bob",
+	Title:            "The door code",
+	Content:          "The door code is 1234",
+	Visibility:       models.VisibilityUnlisted,
+	BurnAfterReading: true,
+	Created:          time.Now(),
+	Expires:          time.Now(),
+}
+
+// mockProtectedSnippet can be unlocked with the passphrase "open sesame".
+var mockProtectedSnippet = &models.Snippet{
+	ID:             6,
+	Slug:           "Pr0tEcT3dS",
+	UserID:         2,
+	Author:         "Bob",
+	Title:          "Wifi password",
+	Content:        "correct horse battery staple",
+	Visibility:     models.VisibilityUnlisted,
+	HashedPassword: []byte("$2a$12$placeholder"),
+	Created:        time.Now(),
+	Expires:        time.Now(),
+}
+
+var mockEncryptedSnippet = &models.Snippet{
+	ID:         7,
+	Slug:       "3nCrYpT3dX",
+	UserID:     1,
+	Author:     "Alice",
+	Title:      "Launch codes",
+	Content:    "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJw",
+	Visibility: models.VisibilityUnlisted,
+	Encrypted:  true,
+	Created:    time.Now(),
+	Expires:    time.Now(),
+}
+
+// mockDetectedSnippet had its language detected rather than chosen.
+var mockDetectedSnippet = &models.Snippet{
+	ID:                 8,
+	Slug:               "D3t3cT3dGo",
+	UserID:             1,
+	Author:             "Alice",
+	Title:              "Hello, world",
+	Content:            "package main\n\nfunc main() {}",
+	Language:           "go",
+	LanguageConfidence: 0.87,
+	Visibility:         models.VisibilityUnlisted,
+	Created:            time.Now(),
+	Expires:            time.Now(),
+}
+
+// mockAnonymousSnippet was pasted without an API token, so nobody owns it.
+var mockAnonymousSnippet = &models.Snippet{
+	ID:               9,
+	Slug:             "An0nYm0uSp",
+	Author:           "Anonymous",
+	Title:            "Untitled",
+	Content:          "Nobody owns this",
+	Visibility:       models.VisibilityUnlisted,
+	BurnAfterReading: true,
+	Created:          time.Now(),
+	Expires:          time.Now(),
+}
+
+var mockSnippets = []*models.Snippet{mockSnippet, mockOtherSnippet, mockPrivateSnippet, mockBurnSnippet, mockProtectedSnippet, mockEncryptedSnippet, mockDetectedSnippet, mockAnonymousSnippet}
+
+var mockRevisions = []*models.Revision{
+	{
+		ID:        2,
+		SnippetID: 1,
+		Number:    2,
+		Title:     "An old silent pond",
+		Content:   "An old silent pond...",
+		Created:   time.Now(),
+	},
+	{
+		ID:        1,
+		SnippetID: 1,
+		Number:    1,
+		Title:     "An old pond",
+		Content:   "An old pond...",
+		Created:   time.Now(),
+	},
 }
 
 type SnippetModel struct{}
 
-func (m *SnippetModel) Insert(title, content string, expires int) (int, error) {
-	return 2, nil
+func (m *SnippetModel) Insert(s *models.Snippet, password string) error {
+	s.ID = 2
+	s.Slug = "n3wSn1pp3t"
+	s.Created = time.Now()
+	return nil
 }
-func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
-	switch id {
-	case 1:
-		return mockSnippet, nil
-	default:
-		return nil, models.ErrNoRecord
+
+// lookup mimics the visibility rules of models.SnippetModel.Get.
+func lookup(match func(s *models.Snippet) bool, userID int) (*models.Snippet, error) {
+	for _, s := range mockSnippets {
+		if !match(s) {
+			continue
+		}
+		if s.Visibility == models.VisibilityPrivate && !s.OwnedBy(userID) {
+			return nil, models.ErrNoRecord
+		}
+		return s, nil
 	}
+	return nil, models.ErrNoRecord
+}
+func (m *SnippetModel) Get(id, userID int) (*models.Snippet, error) {
+	return lookup(func(s *models.Snippet) bool { return s.ID == id }, userID)
+}
+func (m *SnippetModel) GetBySlug(slug string, userID int) (*models.Snippet, error) {
+	return lookup(func(s *models.Snippet) bool { return s.Slug == slug }, userID)
 }
 func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 	return []*models.Snippet{mockSnippet}, nil
 }
+func (m *SnippetModel) ByUser(userID int) ([]*models.Snippet, error) {
+	snippets := []*models.Snippet{}
+	for _, s := range mockSnippets {
+		if s.UserID == userID {
+			snippets = append(snippets, s)
+		}
+	}
+	return snippets, nil
+}
+func (m *SnippetModel) Update(snippet *models.Snippet) error {
+	for _, s := range mockSnippets {
+		if s.ID == snippet.ID {
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
+func (m *SnippetModel) SetLanguage(id int, language string, confidence float64) error {
+	for _, s := range mockSnippets {
+		if s.ID == id {
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
+func (m *SnippetModel) Delete(id int) error {
+	for _, s := range mockSnippets {
+		if s.ID == id {
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
+func (m *SnippetModel) Consume(id int) (*models.Snippet, error) {
+	for _, s := range []*models.Snippet{mockBurnSnippet, mockAnonymousSnippet} {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+func (m *SnippetModel) DeleteExpired(limit int) ([]int, error) {
+	return []int{}, nil
+}
+
+// Archive mimics models.SnippetModel.Archive. Sort keys are compared at the
+// one second precision of the database.
+func (m *SnippetModel) Archive(order models.SnippetOrder, cursor models.Cursor, limit int) ([]*models.Snippet, error) {
+	less := func(a, b models.Cursor) bool {
+		if a.Time.Unix() != b.Time.Unix() {
+			return a.Time.Unix() < b.Time.Unix()
+		}
+		return a.ID < b.ID
+	}
+	ascending := order != models.OrderNewest
+	snippets := []*models.Snippet{}
+	for _, s := range mockSnippets {
+		if s.Visibility != models.VisibilityPublic || s.BurnAfterReading || s.Encrypted || s.PasswordProtected() {
+			continue
+		}
+		if order == models.OrderExpiring && s.NeverExpires() {
+			continue
+		}
+		key := order.Cursor(s)
+		if !cursor.IsZero() {
+			after := less(cursor, key)
+			if !ascending {
+				after = less(key, cursor)
+			}
+			if after == cursor.Before || key.ID == cursor.ID {
+				continue
+			}
+		}
+		snippets = append(snippets, s)
+	}
+	sort.Slice(snippets, func(i, j int) bool {
+		if ascending {
+			return less(order.Cursor(snippets[i]), order.Cursor(snippets[j]))
+		}
+		return less(order.Cursor(snippets[j]), order.Cursor(snippets[i]))
+	})
+	if limit < len(snippets) {
+		if cursor.Before {
+			snippets = snippets[len(snippets)-limit:]
+		} else {
+			snippets = snippets[:limit]
+		}
+	}
+	return snippets, nil
+}
+func (m *SnippetModel) ByTag(tag string, offset, limit int) ([]*models.Snippet, error) {
+	snippets := []*models.Snippet{}
+	for _, s := range mockSnippets {
+		if s.Visibility != models.VisibilityPublic {
+			continue
+		}
+		for _, t := range s.Tags {
+			if t == tag {
+				snippets = append(snippets, s)
+				break
+			}
+		}
+	}
+	if offset > len(snippets) {
+		offset = len(snippets)
+	}
+	snippets = snippets[offset:]
+	if limit < len(snippets) {
+		snippets = snippets[:limit]
+	}
+	return snippets, nil
+}
+func (m *SnippetModel) TagCloud(limit int) ([]*models.Tag, error) {
+	return []*models.Tag{{Name: "poetry", Count: 2}, {Name: "haiku", Count: 1}}, nil
+}
+
+// Search matches the query as a case-insensitive substring, which is close
+// enough to MySQL's full-text search for the handler tests.
+// searchable mimics the visibility rules of models.SnippetModel.Search.
+func searchable(s *models.Snippet, userID int) bool {
+	listed := s.Visibility == models.VisibilityPublic && !s.BurnAfterReading && !s.Encrypted && !s.PasswordProtected()
+	return s.OwnedBy(userID) || listed
+}
+func (m *SnippetModel) Search(query string, userID, offset, limit int) ([]*models.Snippet, error) {
+	query = strings.ToLower(query)
+	snippets := []*models.Snippet{}
+	for _, s := range mockSnippets {
+		if !searchable(s, userID) {
+			continue
+		}
+		if strings.Contains(strings.ToLower(s.Title), query) || strings.Contains(strings.ToLower(s.Content), query) {
+			snippets = append(snippets, s)
+		}
+	}
+	if offset > len(snippets) {
+		offset = len(snippets)
+	}
+	snippets = snippets[offset:]
+	if limit < len(snippets) {
+		snippets = snippets[:limit]
+	}
+	return snippets, nil
+}
+func (m *SnippetModel) Searchable(ids []int, userID int) ([]*models.Snippet, error) {
+	snippets := []*models.Snippet{}
+	for _, id := range ids {
+		for _, s := range mockSnippets {
+			if s.ID == id && searchable(s, userID) {
+				snippets = append(snippets, s)
+			}
+		}
+	}
+	return snippets, nil
+}
+func (m *SnippetModel) Each(fn func(*models.Snippet) error) error {
+	for _, s := range mockSnippets {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (m *SnippetModel) Unlock(id int, password string) error {
+	if id != mockProtectedSnippet.ID {
+		return models.ErrNoRecord
+	}
+	if password != "open sesame" {
+		return models.ErrInvalidCredentials
+	}
+	return nil
+}
+func (m *SnippetModel) Revisions(snippetID int) ([]*models.Revision, error) {
+	switch snippetID {
+	case 1:
+		return mockRevisions, nil
+	default:
+		return []*models.Revision{}, nil
+	}
+}
+func (m *SnippetModel) Revision(snippetID, number int) (*models.Revision, error) {
+	if snippetID == 1 {
+		for _, r := range mockRevisions {
+			if r.Number == number {
+				return r, nil
+			}
+		}
+	}
+	return nil, models.ErrNoRecord
+}
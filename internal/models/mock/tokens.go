@@ -0,0 +1,83 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/models"
+)
+
+// mockToken authenticates as the mock user with every scope,
+// mockReadOnlyToken only with the read scope and mockWriteOnlyToken only
+// with the write scope. mockUnverifiedToken belongs to the mock user who
+// hasn't verified their address.
+const (
+	mockToken           = "sb_valid"
+	mockReadOnlyToken   = "sb_readonly"
+	mockWriteOnlyToken  = "sb_writeonly"
+	mockUnverifiedToken = "sb_unverified"
+)
+
+var mockTokenRecord = &models.Token{
+	ID:      1,
+	UserID:  1,
+	Name:    "laptop",
+	Scopes:  models.Scopes,
+	Created: time.Now(),
+}
+
+var mockReadOnlyTokenRecord = &models.Token{
+	ID:      3,
+	UserID:  1,
+	Name:    "dashboard",
+	Scopes:  []models.Scope{models.ScopeRead},
+	Created: time.Now(),
+	Expires: time.Now().Add(30 * 24 * time.Hour),
+}
+
+var mockWriteOnlyTokenRecord = &models.Token{
+	ID:      5,
+	UserID:  1,
+	Name:    "uploader",
+	Scopes:  []models.Scope{models.ScopeWrite},
+	Created: time.Now(),
+}
+
+var mockUnverifiedTokenRecord = &models.Token{
+	ID:      4,
+	UserID:  3,
+	Name:    "cli",
+	Scopes:  models.Scopes,
+	Created: time.Now(),
+}
+
+type TokenModel struct{}
+
+func (m *TokenModel) New(userID int, name string, scopes []models.Scope, expires time.Time) (*models.Token, string, error) {
+	return &models.Token{ID: 2, UserID: userID, Name: name, Scopes: scopes, Created: time.Now(), Expires: expires}, "sb_n3wT0k3n", nil
+}
+func (m *TokenModel) Authenticate(token string) (*models.Token, error) {
+	switch token {
+	case mockToken:
+		return mockTokenRecord, nil
+	case mockReadOnlyToken:
+		return mockReadOnlyTokenRecord, nil
+	case mockWriteOnlyToken:
+		return mockWriteOnlyTokenRecord, nil
+	case mockUnverifiedToken:
+		return mockUnverifiedTokenRecord, nil
+	default:
+		return nil, models.ErrInvalidCredentials
+	}
+}
+func (m *TokenModel) ByUser(userID int) ([]*models.Token, error) {
+	if userID == mockTokenRecord.UserID {
+		return []*models.Token{mockReadOnlyTokenRecord, mockTokenRecord}, nil
+	}
+	return []*models.Token{}, nil
+}
+func (m *TokenModel) Delete(id, userID int) error {
+	if id == mockTokenRecord.ID && userID == mockTokenRecord.UserID {
+		return nil
+	}
+	return models.ErrNoRecord
+}
@@ -7,33 +7,44 @@ import (
 )
 
 var mockUser = &models.User{
-	ID:      1,
-	Name:    "Alice",
-	Email:   "alice@example.com",
+	ID:       1,
+	Name:     "Alice",
+	Email:    "alice@example.com",
+	Created:  time.Now(),
+	Verified: true,
+}
+
+// mockUnverifiedUser has just signed up and not verified their address yet.
+var mockUnverifiedUser = &models.User{
+	ID:      3,
+	Name:    "Bob",
+	Email:   "bob@example.com",
 	Created: time.Now(),
 }
 
 type UserModel struct{}
 
-func (m *UserModel) Insert(name, email, password string) error {
+func (m *UserModel) Insert(name, email, password string) (int, error) {
 	switch email {
 	case "dupe@example.com":
-		return models.ErrDuplicateEmail
+		return 0, models.ErrDuplicateEmail
 	default:
-		return nil
+		return mockUnverifiedUser.ID, nil
 	}
 }
 func (m *UserModel) Authenticate(email, password string) (int, error) {
 	switch email {
 	case "alice@example.com":
 		return 1, nil
+	case "bob@example.com":
+		return 3, nil
 	default:
 		return 0, models.ErrInvalidCredentials
 	}
 }
 func (m *UserModel) Exists(id int) (bool, error) {
 	switch id {
-	case 1:
+	case 1, 3:
 		return true, nil
 	default:
 		return false, models.ErrNoRecord
@@ -43,6 +54,8 @@ func (m *UserModel) Get(id int) (*models.User, error) {
 	switch id {
 	case 1:
 		return mockUser, nil
+	case 3:
+		return mockUnverifiedUser, nil
 	default:
 		return nil, models.ErrNoRecord
 	}
@@ -56,3 +69,11 @@ func (m *UserModel) PasswordUpdate(id int, currentPassword, newPassword string)
 	}
 	return models.ErrNoRecord
 }
+func (m *UserModel) Verify(id int) error {
+	switch id {
+	case 1, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
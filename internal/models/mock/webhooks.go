@@ -0,0 +1,61 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/models"
+)
+
+var mockWebhook = &models.Webhook{
+	ID:      1,
+	UserID:  1,
+	URL:     "https://example.com/hooks/snippets",
+	Events:  []models.Event{models.EventSnippetCreated, models.EventSnippetDeleted},
+	Secret:  "s3cr3t",
+	Created: time.Now(),
+}
+
+var mockDelivery = &models.Delivery{
+	ID:             1,
+	WebhookID:      1,
+	URL:            mockWebhook.URL,
+	Secret:         mockWebhook.Secret,
+	Event:          models.EventSnippetCreated,
+	Payload:        []byte(`{"event":"snippet.created"}`),
+	Status:         models.DeliveryFailed,
+	Attempts:       8,
+	ResponseStatus: 500,
+	Error:          "receiver replied 500 Internal Server Error",
+	Created:        time.Now(),
+	LastAttempt:    time.Now(),
+}
+
+type WebhookModel struct{}
+
+func (m *WebhookModel) Insert(userID int, url string, events []models.Event) (*models.Webhook, error) {
+	return &models.Webhook{ID: 2, UserID: userID, URL: url, Events: events, Secret: "n3wS3cr3t", Created: time.Now()}, nil
+}
+func (m *WebhookModel) ByUser(userID int) ([]*models.Webhook, error) {
+	if userID == mockWebhook.UserID {
+		return []*models.Webhook{mockWebhook}, nil
+	}
+	return []*models.Webhook{}, nil
+}
+func (m *WebhookModel) Delete(id, userID int) error {
+	if id == mockWebhook.ID && userID == mockWebhook.UserID {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+func (m *WebhookModel) Due(limit int) ([]*models.Delivery, error) {
+	return []*models.Delivery{}, nil
+}
+func (m *WebhookModel) Record(d *models.Delivery) error {
+	return nil
+}
+func (m *WebhookModel) Deliveries(userID, limit int) ([]*models.Delivery, error) {
+	if userID == mockWebhook.UserID {
+		return []*models.Delivery{mockDelivery}, nil
+	}
+	return []*models.Delivery{}, nil
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type PasswordResetModelInterface interface {
+	New(email string, ttl time.Duration) (*User, string, error)
+	Check(token string) (int, error)
+	Reset(token, password string) (int, error)
+}
+
+// PasswordResetModel manages the links that let users who forgot their
+// password choose a new one. Like API tokens, only a hash of each token is
+// stored, and a token stops working when it expires or once it was used.
+type PasswordResetModel struct {
+	DB *sql.DB
+}
+
+// New creates a token that resets the password of the user with the given
+// email address within ttl, and returns the user along with the token's
+// plaintext. Unknown addresses get ErrNoRecord.
+func (m *PasswordResetModel) New(email string, ttl time.Duration) (*User, string, error) {
+	user := &User{}
+	stmt := `SELECT id, name, email, created, verified FROM users WHERE email = ?`
+	err := m.DB.QueryRow(stmt, email).Scan(&user.ID, &user.Name, &user.Email, &user.Created, &user.Verified)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrNoRecord
+		}
+		return nil, "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	plaintext := base64.RawURLEncoding.EncodeToString(b)
+	now := time.Now().UTC().Truncate(time.Second)
+	// Expired tokens of the user are cleared out on the way.
+	stmt = `DELETE FROM password_resets WHERE user_id = ? AND expires <= UTC_TIMESTAMP()`
+	if _, err = m.DB.Exec(stmt, user.ID); err != nil {
+		return nil, "", err
+	}
+	stmt = `INSERT INTO password_resets (user_id, hashed_token, created, expires)
+	VALUES (?, ?, ?, ?)`
+	_, err = m.DB.Exec(stmt, user.ID, hashToken(plaintext), now, now.Add(ttl))
+	if err != nil {
+		return nil, "", err
+	}
+	return user, plaintext, nil
+}
+
+// Check returns the ID of the user a token would reset the password of.
+// Unknown, used and expired tokens get ErrInvalidCredentials.
+func (m *PasswordResetModel) Check(token string) (int, error) {
+	stmt := `SELECT user_id FROM password_resets
+	WHERE hashed_token = ? AND expires > UTC_TIMESTAMP()`
+	var userID int
+	err := m.DB.QueryRow(stmt, hashToken(token)).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// Reset sets the password of the user a token was created for and returns
+// their ID. It uses up every reset token of the user, so that older links
+// can't undo the change. Unknown, used and expired tokens get
+// ErrInvalidCredentials.
+func (m *PasswordResetModel) Reset(token, password string) (int, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Locking the row makes two requests with the same token wait for each
+	// other, so that only the first one resets the password.
+	stmt := `SELECT user_id FROM password_resets
+	WHERE hashed_token = ? AND expires > UTC_TIMESTAMP()
+	FOR UPDATE`
+	var userID int
+	err = tx.QueryRow(stmt, hashToken(token)).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, err
+	}
+	stmt = `UPDATE users SET hashed_password = ? WHERE id = ?`
+	if _, err = tx.Exec(stmt, hashedPassword, userID); err != nil {
+		return 0, err
+	}
+	stmt = `DELETE FROM password_resets WHERE user_id = ?`
+	if _, err = tx.Exec(stmt, userID); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
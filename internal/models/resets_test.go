@@ -0,0 +1,51 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestPasswordResetModel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := PasswordResetModel{db}
+	users := UserModel{db}
+
+	_, _, err := m.New("nobody@example.com", time.Hour)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	user, older, err := m.New("alice@example.com", time.Hour)
+	assert.NilError(t, err)
+	assert.Equal(t, user.ID, 1)
+	_, token, err := m.New("alice@example.com", time.Hour)
+	assert.NilError(t, err)
+	_, expired, err := m.New("alice@example.com", -time.Minute)
+	assert.NilError(t, err)
+
+	id, err := m.Check(token)
+	assert.NilError(t, err)
+	assert.Equal(t, id, 1)
+	_, err = m.Check(expired)
+	assert.Equal(t, errors.Is(err, ErrInvalidCredentials), true)
+	_, err = m.Reset(expired, "n3wPa$$word")
+	assert.Equal(t, errors.Is(err, ErrInvalidCredentials), true)
+
+	id, err = m.Reset(token, "n3wPa$$word")
+	assert.NilError(t, err)
+	assert.Equal(t, id, 1)
+	id, err = users.Authenticate("alice@example.com", "n3wPa$$word")
+	assert.NilError(t, err)
+	assert.Equal(t, id, 1)
+
+	// The token was used up, and so were the older ones.
+	_, err = m.Reset(token, "an0therPa$$word")
+	assert.Equal(t, errors.Is(err, ErrInvalidCredentials), true)
+	_, err = m.Check(older)
+	assert.Equal(t, errors.Is(err, ErrInvalidCredentials), true)
+}
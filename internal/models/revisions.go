@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Revision is an immutable copy of a snippet's title and content, recorded
+// whenever either of them changes. Revision numbers start at 1 for every
+// snippet.
+type Revision struct {
+	ID        int
+	SnippetID int
+	Number    int
+	Title     string
+	Content   string
+	Created   time.Time
+}
+
+// Revisions returns every revision of a snippet, newest first.
+func (m *SnippetModel) Revisions(snippetID int) ([]*Revision, error) {
+	stmt := `SELECT id, snippet_id, revision, title, content, created FROM snippet_revisions
+	WHERE snippet_id = ?
+	ORDER BY revision DESC`
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	revisions := []*Revision{}
+	for rows.Next() {
+		r := &Revision{}
+		if err := rows.Scan(&r.ID, &r.SnippetID, &r.Number, &r.Title, &r.Content, &r.Created); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (m *SnippetModel) Revision(snippetID, number int) (*Revision, error) {
+	stmt := `SELECT id, snippet_id, revision, title, content, created FROM snippet_revisions
+	WHERE snippet_id = ? AND revision = ?`
+	r := &Revision{}
+	err := m.DB.QueryRow(stmt, snippetID, number).Scan(&r.ID, &r.SnippetID, &r.Number, &r.Title, &r.Content, &r.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// insertRevision records the given title and content as the next revision of
+// a snippet. Callers must hold a lock on the snippet row so that concurrent
+// edits can't race for the same revision number.
+func insertRevision(tx *sql.Tx, snippetID int, title, content string) error {
+	var latest int
+	stmt := `SELECT COALESCE(MAX(revision), 0) FROM snippet_revisions WHERE snippet_id = ?`
+	if err := tx.QueryRow(stmt, snippetID).Scan(&latest); err != nil {
+		return err
+	}
+	stmt = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+	VALUES (?, ?, ?, ?, UTC_TIMESTAMP())`
+	_, err := tx.Exec(stmt, snippetID, latest+1, title, content)
+	return err
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// searchableBy is the condition for snippets the user in the single
+// parameter can find by searching: their own and listed ones, but not
+// password-protected ones of other users since matching them would reveal
+// their content.
+const searchableBy = `(s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+	AND (s.user_id = ? OR (s.visibility = 'public' AND NOT s.burn_after_reading AND NOT s.encrypted AND s.hashed_password IS NULL))`
+
+// Search returns at most limit snippets whose title or content match query,
+// most relevant first, skipping the first offset of them. Only snippets
+// userID can find are returned (see searchableBy).
+func (m *SnippetModel) Search(query string, userID, offset, limit int) ([]*Snippet, error) {
+	stmt := `SELECT s.id, s.slug, COALESCE(s.user_id, 0), s.title, s.content, s.visibility, s.burn_after_reading, s.encrypted, s.created, s.expires,
+		MATCH(s.title, s.content) AGAINST (? IN NATURAL LANGUAGE MODE) AS score
+	FROM snippets s
+	WHERE MATCH(s.title, s.content) AGAINST (? IN NATURAL LANGUAGE MODE)
+	AND ` + searchableBy + `
+	ORDER BY score DESC, s.created DESC, s.id DESC
+	LIMIT ? OFFSET ?`
+	rows, err := m.DB.Query(stmt, query, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s := &Snippet{}
+		var expires sql.NullTime
+		var score float64
+		if err := rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Title, &s.Content, &s.Visibility, &s.BurnAfterReading, &s.Encrypted, &s.Created, &expires, &score); err != nil {
+			return nil, err
+		}
+		s.Expires = expires.Time
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// Searchable returns the snippets among ids that userID can find by
+// searching, in no particular order. It lets a search index that doesn't
+// know about visibility or expiry leave those checks to the database.
+func (m *SnippetModel) Searchable(ids []int, userID int) ([]*Snippet, error) {
+	snippets := []*Snippet{}
+	if len(ids) == 0 {
+		return snippets, nil
+	}
+	args := make([]any, 0, len(ids)+1)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, userID)
+	stmt := `SELECT s.id, s.slug, COALESCE(s.user_id, 0), s.title, s.content, s.visibility, s.burn_after_reading, s.encrypted, s.created, s.expires
+	FROM snippets s
+	WHERE s.id IN (?` + strings.Repeat(`, ?`, len(ids)-1) + `)
+	AND ` + searchableBy
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		s := &Snippet{}
+		var expires sql.NullTime
+		if err := rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Title, &s.Content, &s.Visibility, &s.BurnAfterReading, &s.Encrypted, &s.Created, &expires); err != nil {
+			return nil, err
+		}
+		s.Expires = expires.Time
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// Each calls fn with the ID, title, content and Encrypted flag of every
+// snippet that hasn't expired, stopping at the first error. It is used to
+// build search indexes.
+func (m *SnippetModel) Each(fn func(*Snippet) error) error {
+	stmt := `SELECT id, title, content, encrypted FROM snippets
+	WHERE expires IS NULL OR expires > UTC_TIMESTAMP()`
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		s := &Snippet{}
+		if err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Encrypted); err != nil {
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
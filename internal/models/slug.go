@@ -0,0 +1,63 @@
+package models
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+const (
+	slugLength   = 10
+	slugAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// maxSlugAttempts bounds how often Insert retries after a slug
+	// collision. With 62^10 possible slugs a second attempt is already
+	// astronomically unlikely.
+	maxSlugAttempts = 5
+)
+
+// newSlug returns a random, URL-safe base62 identifier. Slugs always contain
+// at least one letter so they can never be mistaken for a numeric ID.
+func newSlug() (string, error) {
+	var sb strings.Builder
+	buf := make([]byte, slugLength*2)
+	for {
+		sb.Reset()
+		hasLetter := false
+		for sb.Len() < slugLength {
+			if _, err := rand.Read(buf); err != nil {
+				return "", err
+			}
+			for _, b := range buf {
+				// Reject bytes past the largest multiple of the alphabet size
+				// so every character is equally likely.
+				if int(b) >= 256-256%len(slugAlphabet) {
+					continue
+				}
+				c := slugAlphabet[int(b)%len(slugAlphabet)]
+				if c > '9' {
+					hasLetter = true
+				}
+				sb.WriteByte(c)
+				if sb.Len() == slugLength {
+					break
+				}
+			}
+		}
+		if hasLetter {
+			return sb.String(), nil
+		}
+	}
+}
+
+// IsNumericID reports whether an identifier taken from a URL is a legacy
+// numeric snippet ID rather than a slug.
+func IsNumericID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
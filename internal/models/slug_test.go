@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestNewSlug(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		slug, err := newSlug()
+		assert.NilError(t, err)
+		assert.Equal(t, len(slug), slugLength)
+		for _, c := range slug {
+			if !strings.ContainsRune(slugAlphabet, c) {
+				t.Fatalf("slug %q contains %q, which is not in the alphabet", slug, c)
+			}
+		}
+		assert.Equal(t, IsNumericID(slug), false)
+		if seen[slug] {
+			t.Fatalf("slug %q was generated twice", slug)
+		}
+		seen[slug] = true
+	}
+}
+
+func TestIsNumericID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "Number", id: "42", want: true},
+		{name: "Empty", id: "", want: false},
+		{name: "Negative", id: "-1", want: false},
+		{name: "Decimal", id: "1.23", want: false},
+		{name: "Slug", id: "aB3dE5gH7j", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, IsNumericID(tt.id), tt.want)
+		})
+	}
+}
@@ -3,62 +3,272 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Visibility controls who can find and read a snippet. Public snippets are
+// listed by Latest, unlisted ones are only reachable by their link, and
+// private ones can only be read by their owner.
+type Visibility string
+
+const (
+	VisibilityPublic   Visibility = "public"
+	VisibilityUnlisted Visibility = "unlisted"
+	VisibilityPrivate  Visibility = "private"
 )
 
 type SnippetModelInterface interface {
-	Insert(title, content string, expires int) (int, error)
-	Get(id int) (*Snippet, error)
+	Insert(s *Snippet, password string) error
+	Get(id, userID int) (*Snippet, error)
+	GetBySlug(slug string, userID int) (*Snippet, error)
 	Latest() ([]*Snippet, error)
+	ByUser(userID int) ([]*Snippet, error)
+	Update(s *Snippet) error
+	SetLanguage(id int, language string, confidence float64) error
+	Delete(id int) error
+	Consume(id int) (*Snippet, error)
+	Archive(order SnippetOrder, cursor Cursor, limit int) ([]*Snippet, error)
+	ByTag(tag string, offset, limit int) ([]*Snippet, error)
+	TagCloud(limit int) ([]*Tag, error)
+	Search(query string, userID, offset, limit int) ([]*Snippet, error)
+	Searchable(ids []int, userID int) ([]*Snippet, error)
+	Each(fn func(*Snippet) error) error
+	DeleteExpired(limit int) ([]int, error)
+	Unlock(id int, password string) error
+	Revisions(snippetID int) ([]*Revision, error)
+	Revision(snippetID, number int) (*Revision, error)
 }
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
+	ID         int
+	Slug       string
+	UserID     int
+	Author     string
+	Title      string
+	Content    string
+	Visibility Visibility
+	// BurnAfterReading snippets are deleted the first time somebody other
+	// than their owner reads them.
+	BurnAfterReading bool
+	// HashedPassword is set when the snippet can only be read after
+	// entering a passphrase.
+	HashedPassword []byte
+	// Encrypted snippets were encrypted in the browser before they were
+	// submitted. Their Content is ciphertext (see IsCiphertext) and the key
+	// never reaches the server.
+	Encrypted bool
+	// Language is the name of the language the content is highlighted as
+	// (see the syntax package), empty for plain text.
+	Language string
+	// LanguageConfidence is how sure the guess was when Language was
+	// detected rather than chosen by the author, in which case it is 0.
+	LanguageConfidence float64
+	// Tags are only loaded for single snippets, not for listings.
+	Tags    []string
 	Created time.Time
+	// Expires is the zero time for snippets that never expire.
 	Expires time.Time
 }
+
+func (s *Snippet) PasswordProtected() bool {
+	return len(s.HashedPassword) > 0
+}
+
+// OwnedBy reports whether the user with ID userID owns the snippet. Nobody
+// owns anonymous snippets, not even anonymous visitors.
+func (s *Snippet) OwnedBy(userID int) bool {
+	return s.UserID != 0 && s.UserID == userID
+}
+
+func (s *Snippet) NeverExpires() bool {
+	return s.Expires.IsZero()
+}
+
+// nullUserID maps the zero user ID of anonymous snippets to NULL.
+func nullUserID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// nullTime maps the zero time, which Snippet.Expires uses for "never", to
+// NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
 type SnippetModel struct {
 	DB *sql.DB
 }
 
-func (m *SnippetModel) Insert(title, content string, expires int) (int, error) {
-	stmnt := `INSERT INTO snippets (title,content,created, expires) 
+// Insert stores a new snippet owned by s.UserID, or by nobody when it is
+// zero, and fills in its ID, Slug and
+// Created fields. The slug is generated randomly and regenerated if it
+// collides with an existing one. A non-empty password protects the snippet
+// and is stored as a bcrypt hash.
+func (m *SnippetModel) Insert(s *Snippet, password string) error {
+	s.HashedPassword = nil
+	if password != "" {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		s.HashedPassword = hashed
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	s.Created = time.Now().UTC().Truncate(time.Second)
+	stmnt := `INSERT INTO snippets (slug, user_id, title, content, language, language_confidence, visibility, burn_after_reading, hashed_password, encrypted, created, expires)
 	VALUES (
 			?,
 			?,
-			UTC_TIMESTAMP(),
-			DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY)
+			?,
+			?,
+			?,
+			?,
+			?,
+			?,
+			?,
+			?,
+			?,
+			?
 		)`
-	res, err := m.DB.Exec(stmnt, title, content, expires)
-	if err != nil {
-		return 0, err
+	var res sql.Result
+	for attempt := 1; ; attempt++ {
+		s.Slug, err = newSlug()
+		if err != nil {
+			return err
+		}
+		res, err = tx.Exec(stmnt, s.Slug, nullUserID(s.UserID), s.Title, s.Content, s.Language, s.LanguageConfidence, s.Visibility, s.BurnAfterReading, s.HashedPassword, s.Encrypted, s.Created, nullTime(s.Expires))
+		if err == nil {
+			break
+		}
+		// A duplicate key error only rolls back the failed statement, so it
+		// is safe to try again with a fresh slug inside the same transaction.
+		if isSlugCollision(err) && attempt < maxSlugAttempts {
+			continue
+		}
+		return err
 	}
 	id, err := res.LastInsertId()
 	if err != nil {
+		return err
+	}
+	s.ID = int(id)
+	if err = insertRevision(tx, s.ID, s.Title, s.Content); err != nil {
+		return err
+	}
+	if err = setTags(tx, s.ID, s.Tags); err != nil {
+		return err
+	}
+	if err = enqueueWebhooks(tx, EventSnippetCreated, s); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isSlugCollision reports whether err is a violation of the unique key on
+// slugs.
+func isSlugCollision(err error) bool {
+	var mySQLError *mysql.MySQLError
+	return errors.As(err, &mySQLError) && mySQLError.Number == 1062 &&
+		strings.Contains(mySQLError.Message, "snippets_uc_slug")
+}
+
+// BackfillSlugs gives a random slug to every snippet that has none yet,
+// which is the case for snippets created before slugs existed, and returns
+// how many it updated. It is part of the migration described in the readme
+// and can be run again safely.
+func (m *SnippetModel) BackfillSlugs() (int, error) {
+	rows, err := m.DB.Query(`SELECT id FROM snippets WHERE slug IS NULL`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
 		return 0, err
 	}
-	return int(id), nil
+
+	n := 0
+	stmt := `UPDATE snippets SET slug = ? WHERE id = ? AND slug IS NULL`
+	for _, id := range ids {
+		for attempt := 1; ; attempt++ {
+			slug, err := newSlug()
+			if err != nil {
+				return n, err
+			}
+			res, err := m.DB.Exec(stmt, slug, id)
+			if isSlugCollision(err) && attempt < maxSlugAttempts {
+				continue
+			}
+			if err != nil {
+				return n, err
+			}
+			// The snippet may have been deleted since, or given a slug by
+			// another run.
+			if updated, err := res.RowsAffected(); err == nil && updated == 1 {
+				n++
+			}
+			break
+		}
+	}
+	return n, nil
+}
+
+// Get returns the snippet with the given ID as seen by the user with ID
+// userID (zero for anonymous visitors). Private snippets of other users are
+// reported as ErrNoRecord so that their existence isn't leaked.
+func (m *SnippetModel) Get(id, userID int) (*Snippet, error) {
+	return m.get("s.id = ?", id, userID)
+}
+
+// GetBySlug is like Get but looks the snippet up by its public slug.
+func (m *SnippetModel) GetBySlug(slug string, userID int) (*Snippet, error) {
+	return m.get("s.slug = ?", slug, userID)
 }
 
-func (m *SnippetModel) Get(id int) (*Snippet, error) {
-	stmnt := `SELECT id, title, content, created, expires FROM snippets 
-	WHERE expires > UTC_TIMESTAMP() AND id = ?`
-	row := m.DB.QueryRow(stmnt, id)
+func (m *SnippetModel) get(where string, arg any, userID int) (*Snippet, error) {
+	stmnt := `SELECT s.id, s.slug, COALESCE(s.user_id, 0), COALESCE(u.name, 'Anonymous'), s.title, s.content, s.language, s.language_confidence, s.visibility, s.burn_after_reading, s.hashed_password, s.encrypted, s.created, s.expires
+	FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+	WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND ` + where + `
+	AND (s.visibility <> 'private' OR s.user_id = ?)`
+	row := m.DB.QueryRow(stmnt, arg, userID)
 	s := &Snippet{}
-	if err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires); err != nil {
+	var expires sql.NullTime
+	err := row.Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.LanguageConfidence, &s.Visibility, &s.BurnAfterReading, &s.HashedPassword, &s.Encrypted, &s.Created, &expires)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoRecord
 		}
 		return nil, err
 	}
-
+	s.Expires = expires.Time
+	s.Tags, err = tagsOf(m.DB, s.ID)
+	if err != nil {
+		return nil, err
+	}
 	return s, nil
 }
 func (m *SnippetModel) Latest() ([]*Snippet, error) {
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-	WHERE expires > UTC_TIMESTAMP()
-	ORDER BY created DESC
+	stmt := `SELECT s.id, s.slug, COALESCE(s.user_id, 0), s.title, s.content, s.visibility, s.burn_after_reading, s.encrypted, s.created, s.expires
+	FROM snippets s
+	WHERE ` + listedSnippets + `
+	ORDER BY s.created DESC
 	LIMIT 10`
 	rows, err := m.DB.Query(stmt)
 	if err != nil {
@@ -68,9 +278,11 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 	snippets := make([]*Snippet, 0, 10)
 	for rows.Next() {
 		s := &Snippet{}
-		if err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires); err != nil {
+		var expires sql.NullTime
+		if err := rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Title, &s.Content, &s.Visibility, &s.BurnAfterReading, &s.Encrypted, &s.Created, &expires); err != nil {
 			return nil, err
 		}
+		s.Expires = expires.Time
 		snippets = append(snippets, s)
 	}
 	if err = rows.Err(); err != nil {
@@ -79,3 +291,274 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 
 	return snippets, nil
 }
+
+// ByUser returns every unexpired snippet owned by the given user, newest first.
+func (m *SnippetModel) ByUser(userID int) ([]*Snippet, error) {
+	stmt := `SELECT id, slug, user_id, title, content, visibility, burn_after_reading, encrypted, created, expires FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND user_id = ?
+	ORDER BY created DESC`
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s := &Snippet{}
+		var expires sql.NullTime
+		if err := rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Title, &s.Content, &s.Visibility, &s.BurnAfterReading, &s.Encrypted, &s.Created, &expires); err != nil {
+			return nil, err
+		}
+		s.Expires = expires.Time
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// Update stores the title, content, expiry, visibility and tags of the
+// snippet with ID s.ID and, when its title or content differ from what is
+// stored, records the new text as a revision.
+func (m *SnippetModel) Update(s *Snippet) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldTitle, oldContent, slug string
+	var owner int
+	var created time.Time
+	stmt := `SELECT title, content, COALESCE(user_id, 0), slug, created FROM snippets WHERE id = ? FOR UPDATE`
+	err = tx.QueryRow(stmt, s.ID).Scan(&oldTitle, &oldContent, &owner, &slug, &created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	if s.Title != oldTitle || s.Content != oldContent {
+		// Snippets created before revisions were tracked have no history yet,
+		// so keep their original text as the first revision.
+		var count int
+		stmt = `SELECT COUNT(*) FROM snippet_revisions WHERE snippet_id = ?`
+		if err = tx.QueryRow(stmt, s.ID).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			stmt = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+			SELECT id, 1, title, content, created FROM snippets WHERE id = ?`
+			if _, err = tx.Exec(stmt, s.ID); err != nil {
+				return err
+			}
+		}
+		if err = insertRevision(tx, s.ID, s.Title, s.Content); err != nil {
+			return err
+		}
+	}
+
+	stmt = `UPDATE snippets SET
+		title = ?,
+		content = ?,
+		language = ?,
+		language_confidence = ?,
+		visibility = ?,
+		expires = ?
+	WHERE id = ?`
+	if _, err = tx.Exec(stmt, s.Title, s.Content, s.Language, s.LanguageConfidence, s.Visibility, nullTime(s.Expires), s.ID); err != nil {
+		return err
+	}
+	if err = setTags(tx, s.ID, s.Tags); err != nil {
+		return err
+	}
+	// The owner is taken from the row rather than trusted from s, and the
+	// fields an edit doesn't change are filled in for the payload.
+	updated := *s
+	updated.UserID = owner
+	updated.Slug = slug
+	updated.Created = created
+	if err = enqueueWebhooks(tx, EventSnippetUpdated, &updated); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetLanguage changes the language a snippet is highlighted as, along with
+// the confidence of its detection (0 when the author chose it).
+func (m *SnippetModel) SetLanguage(id int, language string, confidence float64) error {
+	stmt := `UPDATE snippets SET language = ?, language_confidence = ? WHERE id = ?`
+	res, err := m.DB.Exec(stmt, language, confidence, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Delete deletes a snippet and queues the deliveries of its
+// snippet.deleted event.
+func (m *SnippetModel) Delete(id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id, slug, COALESCE(user_id, 0), title, language, visibility, created, expires
+	FROM snippets WHERE id = ? FOR UPDATE`
+	deleted, err := scanEventSnippet(tx.QueryRow(stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM snippets WHERE id = ?`, id); err != nil {
+		return err
+	}
+	if err = enqueueWebhooks(tx, EventSnippetDeleted, deleted); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// scanEventSnippet reads the fields of a snippet that webhook payloads tell.
+func scanEventSnippet(row interface{ Scan(...any) error }) (*Snippet, error) {
+	s := &Snippet{}
+	var expires sql.NullTime
+	if err := row.Scan(&s.ID, &s.Slug, &s.UserID, &s.Title, &s.Language, &s.Visibility, &s.Created, &expires); err != nil {
+		return nil, err
+	}
+	s.Expires = expires.Time
+	return s, nil
+}
+
+// Consume reads and deletes a burn-after-reading snippet in one transaction.
+// The row is locked while it is read, so when several readers race only the
+// first one gets the snippet and the others get ErrNoRecord.
+func (m *SnippetModel) Consume(id int) (*Snippet, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT s.id, s.slug, COALESCE(s.user_id, 0), COALESCE(u.name, 'Anonymous'), s.title, s.content, s.language, s.language_confidence, s.visibility, s.burn_after_reading, s.hashed_password, s.encrypted, s.created, s.expires
+	FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+	WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.burn_after_reading AND s.id = ?
+	FOR UPDATE`
+	s := &Snippet{}
+	var expires sql.NullTime
+	err = tx.QueryRow(stmt, id).Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.LanguageConfidence, &s.Visibility, &s.BurnAfterReading, &s.HashedPassword, &s.Encrypted, &s.Created, &expires)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	s.Expires = expires.Time
+	s.Tags, err = tagsOf(tx, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec(`DELETE FROM snippets WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	if err = enqueueWebhooks(tx, EventSnippetDeleted, s); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// DeleteExpired deletes up to limit snippets that have expired, oldest first,
+// and returns their IDs. Their revisions go with them, and the deliveries of
+// their snippet.expired events are queued.
+func (m *SnippetModel) DeleteExpired(limit int) ([]int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id, slug, COALESCE(user_id, 0), title, language, visibility, created, expires
+	FROM snippets
+	WHERE expires IS NOT NULL AND expires <= UTC_TIMESTAMP()
+	ORDER BY expires
+	LIMIT ?
+	FOR UPDATE`
+	rows, err := tx.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	expired := []*Snippet{}
+	for rows.Next() {
+		s, err := scanEventSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		expired = append(expired, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(expired))
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	args := make([]any, len(expired))
+	for i, s := range expired {
+		ids[i] = s.ID
+		args[i] = s.ID
+	}
+	stmt = `DELETE FROM snippets WHERE id IN (?` + strings.Repeat(`, ?`, len(ids)-1) + `)`
+	if _, err = tx.Exec(stmt, args...); err != nil {
+		return nil, err
+	}
+	for _, s := range expired {
+		if err = enqueueWebhooks(tx, EventSnippetExpired, s); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Unlock checks the passphrase of a password-protected snippet. It returns
+// ErrInvalidCredentials when the passphrase doesn't match.
+func (m *SnippetModel) Unlock(id int, password string) error {
+	var hashedPassword []byte
+	stmt := `SELECT hashed_password FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND hashed_password IS NOT NULL AND id = ?`
+	err := m.DB.QueryRow(stmt, id).Scan(&hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,452 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestSnippetModelGet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	tests := []struct {
+		name      string
+		snippetID int
+		userID    int
+		wantErr   error
+	}{
+		{
+			name:      "Public snippet",
+			snippetID: 1,
+			userID:    0,
+		},
+		{
+			name:      "Private snippet as owner",
+			snippetID: 2,
+			userID:    1,
+		},
+		{
+			name:      "Private snippet as visitor",
+			snippetID: 2,
+			userID:    0,
+			wantErr:   ErrNoRecord,
+		},
+		{
+			name:      "Never expires",
+			snippetID: 4,
+			userID:    0,
+		},
+		{
+			name:      "Expired",
+			snippetID: 5,
+			userID:    1,
+			wantErr:   ErrNoRecord,
+		},
+		{
+			name:      "Non-existent ID",
+			snippetID: 99,
+			userID:    1,
+			wantErr:   ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			m := SnippetModel{db}
+			s, err := m.Get(tt.snippetID, tt.userID)
+			if tt.wantErr != nil {
+				assert.Equal(t, errors.Is(err, tt.wantErr), true)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, s.ID, tt.snippetID)
+		})
+	}
+}
+
+func TestSnippetModelByUser(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	tests := []struct {
+		name      string
+		userID    int
+		wantCount int
+	}{
+		{
+			name:      "Owner",
+			userID:    1,
+			wantCount: 4,
+		},
+		{
+			name:      "Non-existent user",
+			userID:    2,
+			wantCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			m := SnippetModel{db}
+			snippets, err := m.ByUser(tt.userID)
+			assert.NilError(t, err)
+			assert.Equal(t, len(snippets), tt.wantCount)
+		})
+	}
+}
+
+func TestSnippetModelUpdateRecordsRevisions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{db}
+
+	s := &Snippet{
+		ID:         1,
+		Title:      "An old silent pond",
+		Content:    "A frog jumps into the pond",
+		Visibility: VisibilityPublic,
+		Tags:       []string{"haiku", "poetry"},
+		Expires:    time.Now().AddDate(0, 0, 7),
+	}
+	err := m.Update(s)
+	assert.NilError(t, err)
+	// Changing only the expiry must not add a revision.
+	s.Expires = time.Time{}
+	err = m.Update(s)
+	assert.NilError(t, err)
+
+	s, err = m.Get(1, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, s.NeverExpires(), true)
+	assert.Equal(t, strings.Join(s.Tags, ","), "haiku,poetry")
+
+	revisions, err := m.Revisions(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(revisions), 2)
+	assert.Equal(t, revisions[0].Number, 2)
+	assert.Equal(t, revisions[0].Content, "A frog jumps into the pond")
+	assert.Equal(t, revisions[1].Number, 1)
+	assert.Equal(t, revisions[1].Content, "An old silent pond...")
+}
+
+func TestSnippetModelConsume(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{db}
+
+	t.Run("Not burn after reading", func(t *testing.T) {
+		_, err := m.Consume(1)
+		assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	})
+
+	t.Run("Concurrent readers", func(t *testing.T) {
+		const readers = 5
+		var wg sync.WaitGroup
+		results := make(chan error, readers)
+		for i := 0; i < readers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := m.Consume(3)
+				results <- err
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		succeeded := 0
+		for err := range results {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+		}
+		assert.Equal(t, succeeded, 1)
+
+		_, err := m.Get(3, 1)
+		assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	})
+}
+
+func TestSnippetModelDeleteExpired(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{db}
+
+	ids, err := m.DeleteExpired(10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(ids), 1)
+	assert.Equal(t, ids[0], 5)
+
+	ids, err = m.DeleteExpired(10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(ids), 0)
+
+	// Snippets that never expire or haven't expired yet are kept.
+	snippets, err := m.ByUser(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(snippets), 4)
+}
+
+func TestSnippetModelBackfillSlugs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{db}
+	// Snippets from before slugs existed, in the middle of the migration.
+	_, err := db.Exec(`ALTER TABLE snippets MODIFY slug CHAR(10) CHARACTER SET ascii COLLATE ascii_bin NULL`)
+	assert.NilError(t, err)
+	_, err = db.Exec(`UPDATE snippets SET slug = NULL WHERE id IN (1, 2)`)
+	assert.NilError(t, err)
+
+	n, err := m.BackfillSlugs()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 2)
+	var missing, distinct int
+	err = db.QueryRow(`SELECT COUNT(*) - COUNT(slug), COUNT(DISTINCT slug) FROM snippets WHERE id IN (1, 2)`).Scan(&missing, &distinct)
+	assert.NilError(t, err)
+	assert.Equal(t, missing, 0)
+	assert.Equal(t, distinct, 2)
+
+	// Running it again changes nothing.
+	n, err = m.BackfillSlugs()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 0)
+}
+
+func TestSnippetModelByTag(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	tests := []struct {
+		name      string
+		tag       string
+		offset    int
+		wantSlugs []string
+	}{
+		{
+			// The private snippet tagged poetry isn't listed.
+			name:      "Public only",
+			tag:       "poetry",
+			wantSlugs: []string{"aB3dE5gH7j"},
+		},
+		{
+			name:      "Past the end",
+			tag:       "poetry",
+			offset:    1,
+			wantSlugs: []string{},
+		},
+		{
+			name:      "Unknown tag",
+			tag:       "prose",
+			wantSlugs: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			m := SnippetModel{db}
+			snippets, err := m.ByTag(tt.tag, tt.offset, 10)
+			assert.NilError(t, err)
+			slugs := []string{}
+			for _, s := range snippets {
+				slugs = append(slugs, s.Slug)
+			}
+			assert.Equal(t, strings.Join(slugs, ","), strings.Join(tt.wantSlugs, ","))
+		})
+	}
+}
+
+func TestSnippetModelListingSkipsProtected(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{db}
+	locked := &Snippet{UserID: 1, Title: "Locked", Content: "secret", Visibility: VisibilityPublic, Tags: []string{"poetry"}}
+	assert.NilError(t, m.Insert(locked, "open sesame"))
+
+	archive, err := m.Archive(OrderNewest, Cursor{}, 10)
+	assert.NilError(t, err)
+	tagged, err := m.ByTag("poetry", 0, 10)
+	assert.NilError(t, err)
+	latest, err := m.Latest()
+	assert.NilError(t, err)
+	for _, s := range append(append(archive, tagged...), latest...) {
+		if s.ID == locked.ID {
+			t.Errorf("password-protected snippet %s is listed", s.Slug)
+		}
+	}
+}
+
+func TestSnippetModelArchive(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	ten := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	one := time.Date(2022, 1, 1, 13, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		order     SnippetOrder
+		cursor    Cursor
+		limit     int
+		wantSlugs []string
+	}{
+		{
+			// Private, burn-after-reading and expired snippets aren't listed.
+			name:      "Newest",
+			order:     OrderNewest,
+			limit:     10,
+			wantSlugs: []string{"N3v3rExp1r", "aB3dE5gH7j"},
+		},
+		{
+			name:      "Oldest",
+			order:     OrderOldest,
+			limit:     10,
+			wantSlugs: []string{"aB3dE5gH7j", "N3v3rExp1r"},
+		},
+		{
+			name:      "Expiring soon",
+			order:     OrderExpiring,
+			limit:     10,
+			wantSlugs: []string{"aB3dE5gH7j"},
+		},
+		{
+			name:      "Limit",
+			order:     OrderNewest,
+			limit:     1,
+			wantSlugs: []string{"N3v3rExp1r"},
+		},
+		{
+			name:      "After",
+			order:     OrderNewest,
+			cursor:    Cursor{Time: one, ID: 4},
+			limit:     10,
+			wantSlugs: []string{"aB3dE5gH7j"},
+		},
+		{
+			name:      "Before",
+			order:     OrderOldest,
+			cursor:    Cursor{Time: one, ID: 4, Before: true},
+			limit:     10,
+			wantSlugs: []string{"aB3dE5gH7j"},
+		},
+		{
+			name:      "Nothing before the start",
+			order:     OrderNewest,
+			cursor:    Cursor{Time: one, ID: 4, Before: true},
+			limit:     10,
+			wantSlugs: []string{},
+		},
+		{
+			name:      "Ties on time are broken by ID",
+			order:     OrderOldest,
+			cursor:    Cursor{Time: ten, ID: 0x7fffffff},
+			limit:     10,
+			wantSlugs: []string{"N3v3rExp1r"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			m := SnippetModel{db}
+			snippets, err := m.Archive(tt.order, tt.cursor, tt.limit)
+			assert.NilError(t, err)
+			slugs := []string{}
+			for _, s := range snippets {
+				slugs = append(slugs, s.Slug)
+			}
+			assert.Equal(t, strings.Join(slugs, ","), strings.Join(tt.wantSlugs, ","))
+		})
+	}
+}
+
+func TestSnippetModelTagCloud(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{db}
+	tags, err := m.TagCloud(10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(tags), 2)
+	assert.Equal(t, *tags[0], Tag{Name: "go", Count: 1})
+	assert.Equal(t, *tags[1], Tag{Name: "poetry", Count: 1})
+}
+
+func TestSnippetModelSearch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		userID    int
+		wantSlugs []string
+	}{
+		{
+			name:      "Content",
+			query:     "pond",
+			wantSlugs: []string{"aB3dE5gH7j"},
+		},
+		{
+			name:      "Private snippet as visitor",
+			query:     "wintry",
+			wantSlugs: []string{},
+		},
+		{
+			name:      "Private snippet as owner",
+			query:     "wintry",
+			userID:    1,
+			wantSlugs: []string{"Zx9Wv8Ut7s"},
+		},
+		{
+			name:      "Expired",
+			query:     "gone",
+			userID:    1,
+			wantSlugs: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			m := SnippetModel{db}
+			snippets, err := m.Search(tt.query, tt.userID, 0, 10)
+			assert.NilError(t, err)
+			slugs := []string{}
+			for _, s := range snippets {
+				slugs = append(slugs, s.Slug)
+			}
+			assert.Equal(t, strings.Join(slugs, ","), strings.Join(tt.wantSlugs, ","))
+		})
+	}
+}
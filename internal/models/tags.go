@@ -0,0 +1,132 @@
+package models
+
+import "database/sql"
+
+// Tag is a topic together with how many listed snippets carry it.
+type Tag struct {
+	Name  string
+	Count int
+}
+
+// listedSnippets is the condition for snippets that may show up in public
+// listings: unexpired public snippets that anyone with the link can read.
+// Like searchableBy, it leaves out password-protected snippets, whose titles
+// alone can give too much away.
+const listedSnippets = `(s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+	AND s.visibility = 'public' AND NOT s.burn_after_reading AND NOT s.encrypted AND s.hashed_password IS NULL`
+
+// ByTag returns at most limit listed snippets tagged with tag, newest first,
+// skipping the first offset of them.
+func (m *SnippetModel) ByTag(tag string, offset, limit int) ([]*Snippet, error) {
+	stmt := `SELECT s.id, s.slug, COALESCE(s.user_id, 0), s.title, s.content, s.visibility, s.burn_after_reading, s.encrypted, s.created, s.expires
+	FROM snippets s
+	INNER JOIN snippet_tags st ON st.snippet_id = s.id
+	INNER JOIN tags t ON t.id = st.tag_id
+	WHERE t.name = ? AND ` + listedSnippets + `
+	ORDER BY s.created DESC, s.id DESC
+	LIMIT ? OFFSET ?`
+	rows, err := m.DB.Query(stmt, tag, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s := &Snippet{}
+		var expires sql.NullTime
+		if err := rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Title, &s.Content, &s.Visibility, &s.BurnAfterReading, &s.Encrypted, &s.Created, &expires); err != nil {
+			return nil, err
+		}
+		s.Expires = expires.Time
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// TagCloud returns the limit tags used by the most listed snippets, most
+// used first.
+func (m *SnippetModel) TagCloud(limit int) ([]*Tag, error) {
+	stmt := `SELECT t.name, COUNT(*) AS uses
+	FROM tags t
+	INNER JOIN snippet_tags st ON st.tag_id = t.id
+	INNER JOIN snippets s ON s.id = st.snippet_id
+	WHERE ` + listedSnippets + `
+	GROUP BY t.id, t.name
+	ORDER BY uses DESC, t.name
+	LIMIT ?`
+	rows, err := m.DB.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []*Tag{}
+	for rows.Next() {
+		t := &Tag{}
+		if err := rows.Scan(&t.Name, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// tagsOf returns the names of a snippet's tags in alphabetical order.
+func tagsOf(q querier, snippetID int) ([]string, error) {
+	stmt := `SELECT t.name FROM tags t
+	INNER JOIN snippet_tags st ON st.tag_id = t.id
+	WHERE st.snippet_id = ?
+	ORDER BY t.name`
+	rows, err := q.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, name)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// setTags replaces the tags of a snippet, creating tags that don't exist yet.
+// The names must be distinct.
+func setTags(tx *sql.Tx, snippetID int, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM snippet_tags WHERE snippet_id = ?`, snippetID); err != nil {
+		return err
+	}
+	for _, name := range tags {
+		// LAST_INSERT_ID(id) makes LastInsertId return the existing tag's ID
+		// when the name is taken.
+		res, err := tx.Exec(`INSERT INTO tags (name) VALUES (?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`, name)
+		if err != nil {
+			return err
+		}
+		tagID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO snippet_tags (snippet_id, tag_id) VALUES (?, ?)`, snippetID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,19 +1,82 @@
 CREATE TABLE snippets (
   id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  slug CHAR(10) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+  user_id INTEGER NULL,
   title VARCHAR(100) NOT NULL,
   content TEXT NOT NULL,
+  language VARCHAR(20) NOT NULL DEFAULT '',
+  language_confidence DECIMAL(3,2) NOT NULL DEFAULT 0,
+  visibility ENUM('public', 'unlisted', 'private') NOT NULL DEFAULT 'public',
+  burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE,
+  hashed_password CHAR(60) NULL,
+  encrypted BOOLEAN NOT NULL DEFAULT FALSE,
   created DATETIME NOT NULL,
-  expires DATETIME NOT NULL
+  expires DATETIME NULL
 );
 
 CREATE INDEX idx_snippets_created ON snippets(created);
+CREATE INDEX idx_snippets_expires ON snippets(expires);
+CREATE FULLTEXT INDEX idx_snippets_fulltext ON snippets(title, content);
+
+ALTER TABLE
+  snippets
+ADD
+  CONSTRAINT snippets_uc_slug UNIQUE (slug);
+
+CREATE TABLE snippet_revisions (
+  id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  snippet_id INTEGER NOT NULL,
+  revision INTEGER NOT NULL,
+  title VARCHAR(100) NOT NULL,
+  content TEXT NOT NULL,
+  created DATETIME NOT NULL
+);
+
+ALTER TABLE
+  snippet_revisions
+ADD
+  CONSTRAINT snippet_revisions_uc_revision UNIQUE (snippet_id, revision);
+
+ALTER TABLE
+  snippet_revisions
+ADD
+  CONSTRAINT fk_snippet_revisions_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+CREATE TABLE tags (
+  id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  name VARCHAR(32) CHARACTER SET ascii COLLATE ascii_bin NOT NULL
+);
+
+ALTER TABLE
+  tags
+ADD
+  CONSTRAINT tags_uc_name UNIQUE (name);
+
+CREATE TABLE snippet_tags (
+  snippet_id INTEGER NOT NULL,
+  tag_id INTEGER NOT NULL,
+  PRIMARY KEY (snippet_id, tag_id)
+);
+
+CREATE INDEX idx_snippet_tags_tag ON snippet_tags(tag_id);
+
+ALTER TABLE
+  snippet_tags
+ADD
+  CONSTRAINT fk_snippet_tags_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+ALTER TABLE
+  snippet_tags
+ADD
+  CONSTRAINT fk_snippet_tags_tag FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE;
 
 CREATE TABLE users (
   id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
   name VARCHAR(255) NOT NULL,
   email VARCHAR(255) NOT NULL,
   hashed_password CHAR(60) NOT NULL,
-  created DATETIME NOT NULL
+  created DATETIME NOT NULL,
+  verified BOOLEAN NOT NULL DEFAULT FALSE
 );
 
 ALTER TABLE
@@ -21,12 +84,163 @@ ALTER TABLE
 ADD
   CONSTRAINT users_uc_email UNIQUE (email);
 
+ALTER TABLE
+  snippets
+ADD
+  CONSTRAINT fk_snippets_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE api_tokens (
+  id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  user_id INTEGER NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  scopes SET('read', 'write', 'delete') NOT NULL,
+  hashed_token CHAR(64) CHARACTER SET ascii NOT NULL,
+  created DATETIME NOT NULL,
+  last_used DATETIME NULL,
+  expires DATETIME NULL
+);
+
+ALTER TABLE
+  api_tokens
+ADD
+  CONSTRAINT api_tokens_uc_hashed_token UNIQUE (hashed_token);
+
+ALTER TABLE
+  api_tokens
+ADD
+  CONSTRAINT fk_api_tokens_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE password_resets (
+  id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  user_id INTEGER NOT NULL,
+  hashed_token CHAR(64) CHARACTER SET ascii NOT NULL,
+  created DATETIME NOT NULL,
+  expires DATETIME NOT NULL
+);
+
+ALTER TABLE
+  password_resets
+ADD
+  CONSTRAINT password_resets_uc_hashed_token UNIQUE (hashed_token);
+
+ALTER TABLE
+  password_resets
+ADD
+  CONSTRAINT fk_password_resets_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE webhooks (
+  id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  user_id INTEGER NOT NULL,
+  url VARCHAR(2048) NOT NULL,
+  events SET('snippet.created', 'snippet.updated', 'snippet.expired', 'snippet.deleted') NOT NULL,
+  secret CHAR(64) CHARACTER SET ascii NOT NULL,
+  created DATETIME NOT NULL
+);
+
+ALTER TABLE
+  webhooks
+ADD
+  CONSTRAINT fk_webhooks_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE webhook_deliveries (
+  id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  webhook_id INTEGER NOT NULL,
+  event VARCHAR(50) NOT NULL,
+  payload TEXT NOT NULL,
+  status ENUM('pending', 'delivered', 'failed') NOT NULL DEFAULT 'pending',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt DATETIME NOT NULL,
+  response_status INTEGER NOT NULL DEFAULT 0,
+  error VARCHAR(255) NOT NULL DEFAULT '',
+  created DATETIME NOT NULL,
+  last_attempt DATETIME NULL
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt);
+
+ALTER TABLE
+  webhook_deliveries
+ADD
+  CONSTRAINT fk_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE;
+
 INSERT INTO
-  users (name, email, hashed_password, created)
+  users (name, email, hashed_password, created, verified)
 VALUES
   (
     'Alice Jones',
     'alice@example.com',
     '$2a$12$NuTjWXm3KKntReFwyBVHyuf/to.HEwTy.eS206TNfkGfr6HzGJSWG',
-    '2022-01-01 10:00:00'
-  );
\ No newline at end of file
+    '2022-01-01 10:00:00',
+    TRUE
+  );
+
+INSERT INTO
+  snippets (slug, user_id, title, content, visibility, created, expires)
+VALUES
+  (
+    'aB3dE5gH7j',
+    1,
+    'An old silent pond',
+    'An old silent pond...',
+    'public',
+    '2022-01-01 10:00:00',
+    '2099-01-01 10:00:00'
+  ),
+  (
+    'Zx9Wv8Ut7s',
+    1,
+    'Over the wintry forest',
+    'Over the wintry forest, winds howl in rage...',
+    'private',
+    '2022-01-01 11:00:00',
+    '2099-01-01 11:00:00'
+  );
+
+INSERT INTO
+  snippets (slug, user_id, title, content, visibility, burn_after_reading, created, expires)
+VALUES
+  (
+    'Bu7nAfT3rR',
+    1,
+    'The door code',
+    '1234',
+    'unlisted',
+    TRUE,
+    '2022-01-01 12:00:00',
+    '2099-01-01 12:00:00'
+  );
+
+INSERT INTO
+  snippets (slug, user_id, title, content, visibility, created, expires)
+VALUES
+  (
+    'N3v3rExp1r',
+    1,
+    'Go proverbs',
+    'Clear is better than clever.',
+    'public',
+    '2022-01-01 13:00:00',
+    NULL
+  ),
+  (
+    'Exp1r3dSn1',
+    1,
+    'Yesterday',
+    'Gone already',
+    'public',
+    '2022-01-01 14:00:00',
+    '2022-01-02 14:00:00'
+  );
+
+INSERT INTO
+  tags (name)
+VALUES
+  ('poetry'),
+  ('go');
+
+INSERT INTO
+  snippet_tags (snippet_id, tag_id)
+VALUES
+  (1, 1),
+  (2, 1),
+  (4, 2);
@@ -1,2 +1,9 @@
-DROP TABLE users;
-DROP TABLE snippets;
\ No newline at end of file
+DROP TABLE snippet_tags;
+DROP TABLE tags;
+DROP TABLE snippet_revisions;
+DROP TABLE snippets;
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
+DROP TABLE password_resets;
+DROP TABLE api_tokens;
+DROP TABLE users;
\ No newline at end of file
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Scope is something an API token is allowed to do. Requests authenticated
+// by a token are refused where the token lacks the scope the route needs;
+// sessions are not limited by scopes.
+type Scope string
+
+const (
+	ScopeRead   Scope = "read"
+	ScopeWrite  Scope = "write"
+	ScopeDelete Scope = "delete"
+)
+
+// Scopes lists every scope in the order they are shown.
+var Scopes = []Scope{ScopeRead, ScopeWrite, ScopeDelete}
+
+type TokenModelInterface interface {
+	New(userID int, name string, scopes []Scope, expires time.Time) (*Token, string, error)
+	Authenticate(token string) (*Token, error)
+	ByUser(userID int) ([]*Token, error)
+	Delete(id, userID int) error
+}
+
+// Token is an API token that lets scripts act on behalf of a user without
+// their password. Only a hash of the token itself is stored.
+type Token struct {
+	ID      int
+	UserID  int
+	Name    string
+	Scopes  []Scope
+	Created time.Time
+	// LastUsed is the zero time for tokens that were never used.
+	LastUsed time.Time
+	// Expires is the zero time for tokens that never expire.
+	Expires time.Time
+}
+
+// HasScope reports whether the token was granted scope.
+func (t *Token) HasScope(scope Scope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// NeverExpires reports whether the token stays valid until it is revoked.
+func (t *Token) NeverExpires() bool {
+	return t.Expires.IsZero()
+}
+
+// joinScopes and splitScopes convert scopes to and from the value of a SET
+// column.
+func joinScopes(scopes []Scope) string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return strings.Join(s, ",")
+}
+
+func splitScopes(s string) []Scope {
+	scopes := []Scope{}
+	for _, scope := range strings.Split(s, ",") {
+		if scope != "" {
+			scopes = append(scopes, Scope(scope))
+		}
+	}
+	return scopes
+}
+
+// tokenPrefix starts every API token so that leaked tokens are easy to
+// recognise.
+const tokenPrefix = "sb_"
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// New creates a token for the user and returns it along with its plaintext,
+// which can't be recovered later. A zero expires creates a token that never
+// expires.
+func (m *TokenModel) New(userID int, name string, scopes []Scope, expires time.Time) (*Token, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	plaintext := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	t := &Token{
+		UserID:  userID,
+		Name:    name,
+		Scopes:  scopes,
+		Created: time.Now().UTC().Truncate(time.Second),
+		Expires: expires,
+	}
+	stmt := `INSERT INTO api_tokens (user_id, name, scopes, hashed_token, created, expires)
+	VALUES (?, ?, ?, ?, ?, ?)`
+	res, err := m.DB.Exec(stmt, t.UserID, t.Name, joinScopes(t.Scopes), hashToken(plaintext), t.Created, nullTime(t.Expires))
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, "", err
+	}
+	t.ID = int(id)
+	return t, plaintext, nil
+}
+
+// Authenticate returns the token with the given plaintext and records that
+// it was used. Unknown and expired tokens get ErrInvalidCredentials.
+func (m *TokenModel) Authenticate(token string) (*Token, error) {
+	stmt := `SELECT id, user_id, name, scopes, created, last_used, expires FROM api_tokens
+	WHERE hashed_token = ? AND (expires IS NULL OR expires > UTC_TIMESTAMP())`
+	t, err := scanToken(m.DB.QueryRow(stmt, hashToken(token)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	stmt = `UPDATE api_tokens SET last_used = UTC_TIMESTAMP() WHERE id = ?`
+	if _, err = m.DB.Exec(stmt, t.ID); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func scanToken(row interface{ Scan(...any) error }) (*Token, error) {
+	t := &Token{}
+	var scopes string
+	var lastUsed, expires sql.NullTime
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &t.Created, &lastUsed, &expires); err != nil {
+		return nil, err
+	}
+	t.Scopes = splitScopes(scopes)
+	t.LastUsed = lastUsed.Time
+	t.Expires = expires.Time
+	return t, nil
+}
+
+// ByUser returns the tokens of a user, newest first, including expired ones.
+func (m *TokenModel) ByUser(userID int) ([]*Token, error) {
+	stmt := `SELECT id, user_id, name, scopes, created, last_used, expires FROM api_tokens
+	WHERE user_id = ?
+	ORDER BY created DESC, id DESC`
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tokens := []*Token{}
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Delete revokes a token of the given user. Tokens of other users are
+// reported as ErrNoRecord.
+func (m *TokenModel) Delete(id, userID int) error {
+	stmt := `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`
+	res, err := m.DB.Exec(stmt, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestTokenModel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := TokenModel{db}
+	token, plaintext, err := m.New(1, "laptop", []Scope{ScopeRead, ScopeDelete}, time.Time{})
+	assert.NilError(t, err)
+	assert.Equal(t, strings.HasPrefix(plaintext, tokenPrefix), true)
+
+	authenticated, err := m.Authenticate(plaintext)
+	assert.NilError(t, err)
+	assert.Equal(t, authenticated.UserID, 1)
+	assert.Equal(t, authenticated.HasScope(ScopeRead), true)
+	assert.Equal(t, authenticated.HasScope(ScopeWrite), false)
+	assert.Equal(t, authenticated.HasScope(ScopeDelete), true)
+	assert.Equal(t, authenticated.NeverExpires(), true)
+	_, err = m.Authenticate(plaintext + "x")
+	assert.Equal(t, errors.Is(err, ErrInvalidCredentials), true)
+
+	tokens, err := m.ByUser(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(tokens), 1)
+	assert.Equal(t, tokens[0].Name, "laptop")
+	assert.Equal(t, tokens[0].LastUsed.IsZero(), false)
+
+	assert.Equal(t, errors.Is(m.Delete(token.ID, 2), ErrNoRecord), true)
+	assert.NilError(t, m.Delete(token.ID, 1))
+	_, err = m.Authenticate(plaintext)
+	assert.Equal(t, errors.Is(err, ErrInvalidCredentials), true)
+
+	_, plaintext, err = m.New(1, "expired", []Scope{ScopeRead}, time.Now().UTC().Add(-time.Minute))
+	assert.NilError(t, err)
+	_, err = m.Authenticate(plaintext)
+	assert.Equal(t, errors.Is(err, ErrInvalidCredentials), true)
+}
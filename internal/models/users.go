@@ -11,11 +11,12 @@ import (
 )
 
 type UserModelInterface interface {
-	Insert(name, email, password string) error
+	Insert(name, email, password string) (int, error)
 	Authenticate(email, password string) (int, error)
 	Exists(id int) (bool, error)
 	Get(id int) (*User, error)
 	PasswordUpdate(id int, currentPassowrd, newPassword string) error
+	Verify(id int) error
 }
 type User struct {
 	ID             int
@@ -23,12 +24,16 @@ type User struct {
 	Email          string
 	HashedPassword []byte
 	Created        time.Time
+	// Verified is set once the user followed the link emailed to them,
+	// proving the address is theirs.
+	Verified bool
 }
 type UserModel struct {
 	DB *sql.DB
 }
 
-func (u *UserModel) Insert(name, email, password string) error {
+// Insert creates an unverified user and returns its ID.
+func (u *UserModel) Insert(name, email, password string) (int, error) {
 	stmt := `INSERT INTO users (name,email, hashed_password, created)
 	VALUES (
 		?,
@@ -38,20 +43,24 @@ func (u *UserModel) Insert(name, email, password string) error {
 	)`
 	ecryptedPass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	_, err = u.DB.Exec(stmt, name, email, string(ecryptedPass))
+	res, err := u.DB.Exec(stmt, name, email, string(ecryptedPass))
 	if err != nil {
 		var mySQLError *mysql.MySQLError
 		if errors.As(err, &mySQLError) {
 			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "user_uc_email") {
-				return ErrDuplicateEmail
+				return 0, ErrDuplicateEmail
 			}
 		}
-		return err
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	return int(id), nil
 }
 
 func (u *UserModel) Authenticate(email, password string) (int, error) {
@@ -86,10 +95,10 @@ func (u *UserModel) Exists(id int) (bool, error) {
 }
 func (u *UserModel) Get(id int) (*User, error) {
 	var user User
-	stmt := `SELECT id, name, email, created FROM users where id = ?`
+	stmt := `SELECT id, name, email, created, verified FROM users where id = ?`
 	res := u.DB.QueryRow(stmt, id)
 
-	err := res.Scan(&user.ID, &user.Name, &user.Email, &user.Created)
+	err := res.Scan(&user.ID, &user.Name, &user.Email, &user.Created, &user.Verified)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoRecord
@@ -126,3 +135,10 @@ func (u *UserModel) PasswordUpdate(id int, currentPassowrd, newPassword string)
 
 	return err
 }
+
+// Verify marks the user's email address as verified.
+func (u *UserModel) Verify(id int) error {
+	stmt := `UPDATE users SET verified = TRUE WHERE id = ?`
+	_, err := u.DB.Exec(stmt, id)
+	return err
+}
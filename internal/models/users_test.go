@@ -44,3 +44,24 @@ func TestUserModelExist(t *testing.T) {
 		})
 	}
 }
+
+func TestUserModelVerify(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := UserModel{db}
+	id, err := m.Insert("Bob", "bob@example.com", "pa$$word")
+	assert.NilError(t, err)
+	assert.Equal(t, id, 2)
+
+	user, err := m.Get(id)
+	assert.NilError(t, err)
+	assert.Equal(t, user.Verified, false)
+
+	assert.NilError(t, m.Verify(id))
+	user, err = m.Get(id)
+	assert.NilError(t, err)
+	assert.Equal(t, user.Verified, true)
+}
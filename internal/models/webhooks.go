@@ -0,0 +1,298 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Event is something that happens to a snippet that webhooks can subscribe
+// to.
+type Event string
+
+const (
+	EventSnippetCreated Event = "snippet.created"
+	EventSnippetUpdated Event = "snippet.updated"
+	EventSnippetExpired Event = "snippet.expired"
+	EventSnippetDeleted Event = "snippet.deleted"
+)
+
+// Events lists every event in the order they are shown.
+var Events = []Event{EventSnippetCreated, EventSnippetUpdated, EventSnippetExpired, EventSnippetDeleted}
+
+// DeliveryStatus is where a webhook delivery is at. Pending deliveries are
+// retried until they succeed or run out of attempts and fail.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+type WebhookModelInterface interface {
+	Insert(userID int, url string, events []Event) (*Webhook, error)
+	ByUser(userID int) ([]*Webhook, error)
+	Delete(id, userID int) error
+	Due(limit int) ([]*Delivery, error)
+	Record(d *Delivery) error
+	Deliveries(userID, limit int) ([]*Delivery, error)
+}
+
+// Webhook is a user's subscription to events on their snippets, which are
+// posted to URL signed with Secret.
+type Webhook struct {
+	ID      int
+	UserID  int
+	URL     string
+	Events  []Event
+	Secret  string
+	Created time.Time
+}
+
+// Delivery is an event on its way to a webhook. Deliveries are queued in the
+// same transaction as the change they report, so none are lost when the
+// server stops before sending them.
+type Delivery struct {
+	ID        int
+	WebhookID int
+	// URL and Secret are those of the webhook.
+	URL     string
+	Secret  string
+	Event   Event
+	Payload []byte
+	Status  DeliveryStatus
+	// Attempts counts the attempts so far, and NextAttempt is when the next
+	// one is due while the delivery is pending.
+	Attempts    int
+	NextAttempt time.Time
+	// ResponseStatus is the status code of the last response, or zero when
+	// there was none, and Error what went wrong with the last attempt.
+	ResponseStatus int
+	Error          string
+	Created        time.Time
+	// LastAttempt is the zero time until the first attempt.
+	LastAttempt time.Time
+}
+
+// WebhookPayload is the JSON body of webhook deliveries.
+type WebhookPayload struct {
+	Event   Event          `json:"event"`
+	Created time.Time      `json:"created"`
+	Snippet WebhookSnippet `json:"snippet"`
+}
+
+// WebhookSnippet is what a delivery tells about the snippet. The content is
+// left out since it can be large, and is only known to the browser for
+// encrypted snippets.
+type WebhookSnippet struct {
+	Slug       string     `json:"slug"`
+	Title      string     `json:"title"`
+	Language   string     `json:"language"`
+	Visibility Visibility `json:"visibility"`
+	Created    time.Time  `json:"created"`
+	// Expires is null for snippets that never expire.
+	Expires *time.Time `json:"expires"`
+}
+
+// enqueueWebhooks queues deliveries of event on s to the webhooks of its
+// owner that subscribed to it, within the transaction making the change.
+// Anonymous snippets have nobody to notify.
+func enqueueWebhooks(tx *sql.Tx, event Event, s *Snippet) error {
+	if s.UserID == 0 {
+		return nil
+	}
+	payload := WebhookPayload{
+		Event:   event,
+		Created: time.Now().UTC().Truncate(time.Second),
+		Snippet: WebhookSnippet{
+			Slug:       s.Slug,
+			Title:      s.Title,
+			Language:   s.Language,
+			Visibility: s.Visibility,
+			Created:    s.Created,
+		},
+	}
+	if !s.NeverExpires() {
+		payload.Snippet.Expires = &s.Expires
+	}
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	stmt := `INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, next_attempt, created)
+	SELECT id, ?, ?, 'pending', 0, ?, ? FROM webhooks
+	WHERE user_id = ? AND FIND_IN_SET(?, events)`
+	_, err = tx.Exec(stmt, event, js, payload.Created, payload.Created, s.UserID, event)
+	return err
+}
+
+// joinEvents and splitEvents convert events to and from the value of a SET
+// column.
+func joinEvents(events []Event) string {
+	s := make([]string, len(events))
+	for i, event := range events {
+		s[i] = string(event)
+	}
+	return strings.Join(s, ",")
+}
+
+func splitEvents(s string) []Event {
+	events := []Event{}
+	for _, event := range strings.Split(s, ",") {
+		if event != "" {
+			events = append(events, Event(event))
+		}
+	}
+	return events
+}
+
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+// Insert subscribes url to events on the user's snippets, with a freshly
+// generated secret.
+func (m *WebhookModel) Insert(userID int, url string, events []Event) (*Webhook, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	w := &Webhook{
+		UserID:  userID,
+		URL:     url,
+		Events:  events,
+		Secret:  hex.EncodeToString(b),
+		Created: time.Now().UTC().Truncate(time.Second),
+	}
+	stmt := `INSERT INTO webhooks (user_id, url, events, secret, created)
+	VALUES (?, ?, ?, ?, ?)`
+	res, err := m.DB.Exec(stmt, w.UserID, w.URL, joinEvents(w.Events), w.Secret, w.Created)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	w.ID = int(id)
+	return w, nil
+}
+
+// ByUser returns the webhooks of a user, newest first.
+func (m *WebhookModel) ByUser(userID int) ([]*Webhook, error) {
+	stmt := `SELECT id, user_id, url, events, secret, created FROM webhooks
+	WHERE user_id = ?
+	ORDER BY created DESC, id DESC`
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		w := &Webhook{}
+		var events string
+		if err := rows.Scan(&w.ID, &w.UserID, &w.URL, &events, &w.Secret, &w.Created); err != nil {
+			return nil, err
+		}
+		w.Events = splitEvents(events)
+		webhooks = append(webhooks, w)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// Delete removes a webhook of the given user along with its deliveries.
+// Webhooks of other users are reported as ErrNoRecord.
+func (m *WebhookModel) Delete(id, userID int) error {
+	stmt := `DELETE FROM webhooks WHERE id = ? AND user_id = ?`
+	res, err := m.DB.Exec(stmt, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+const deliveryColumns = `d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.status, d.attempts, d.next_attempt, d.response_status, d.error, d.created, d.last_attempt`
+
+func scanDelivery(row interface{ Scan(...any) error }) (*Delivery, error) {
+	d := &Delivery{}
+	var lastAttempt sql.NullTime
+	err := row.Scan(&d.ID, &d.WebhookID, &d.URL, &d.Secret, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttempt, &d.ResponseStatus, &d.Error, &d.Created, &lastAttempt)
+	if err != nil {
+		return nil, err
+	}
+	d.LastAttempt = lastAttempt.Time
+	return d, nil
+}
+
+func (m *WebhookModel) deliveries(stmt string, args ...any) ([]*Delivery, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []*Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Due returns up to limit pending deliveries whose next attempt is due,
+// oldest first.
+func (m *WebhookModel) Due(limit int) ([]*Delivery, error) {
+	stmt := `SELECT ` + deliveryColumns + `
+	FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+	WHERE d.status = 'pending' AND d.next_attempt <= UTC_TIMESTAMP()
+	ORDER BY d.next_attempt, d.id
+	LIMIT ?`
+	return m.deliveries(stmt, limit)
+}
+
+// Record stores the outcome of an attempt at a delivery.
+func (m *WebhookModel) Record(d *Delivery) error {
+	stmt := `UPDATE webhook_deliveries SET
+		status = ?,
+		attempts = ?,
+		next_attempt = ?,
+		response_status = ?,
+		error = ?,
+		last_attempt = ?
+	WHERE id = ?`
+	// The webhook may have been deleted in the meantime, in which case
+	// there is nothing left to update.
+	_, err := m.DB.Exec(stmt, d.Status, d.Attempts, d.NextAttempt, d.ResponseStatus, d.Error, nullTime(d.LastAttempt), d.ID)
+	return err
+}
+
+// Deliveries returns the latest limit deliveries to the webhooks of a user,
+// newest first.
+func (m *WebhookModel) Deliveries(userID, limit int) ([]*Delivery, error) {
+	stmt := `SELECT ` + deliveryColumns + `
+	FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+	WHERE w.user_id = ?
+	ORDER BY d.created DESC, d.id DESC
+	LIMIT ?`
+	return m.deliveries(stmt, userID, limit)
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestWebhookModel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := WebhookModel{db}
+	snippets := SnippetModel{db}
+	webhook, err := m.Insert(1, "https://example.com/hook", []Event{EventSnippetCreated, EventSnippetUpdated, EventSnippetDeleted, EventSnippetExpired})
+	assert.NilError(t, err)
+	assert.Equal(t, len(webhook.Secret), 64)
+
+	webhooks, err := m.ByUser(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(webhooks), 1)
+	assert.Equal(t, len(webhooks[0].Events), 4)
+
+	// Creating a snippet queues a delivery in the same transaction.
+	s := &Snippet{UserID: 1, Title: "Hooked", Content: "echo hello", Visibility: VisibilityPublic, Expires: time.Now().UTC().Add(time.Hour)}
+	assert.NilError(t, snippets.Insert(s, ""))
+	due, err := m.Due(10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(due), 1)
+	assert.Equal(t, due[0].Event, EventSnippetCreated)
+	assert.Equal(t, due[0].URL, "https://example.com/hook")
+	assert.Equal(t, due[0].Secret, webhook.Secret)
+	var payload WebhookPayload
+	assert.NilError(t, json.Unmarshal(due[0].Payload, &payload))
+	assert.Equal(t, payload.Snippet.Slug, s.Slug)
+
+	due[0].Status = DeliveryDelivered
+	due[0].Attempts = 1
+	due[0].ResponseStatus = 204
+	due[0].LastAttempt = time.Now().UTC()
+	assert.NilError(t, m.Record(due[0]))
+	due, err = m.Due(10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(due), 0)
+
+	// Edits carry only the fields that can change, like the edit form's;
+	// the payload still has the rest of the snippet.
+	edit := &Snippet{ID: s.ID, Title: "Hooked again", Content: s.Content, Visibility: s.Visibility, Expires: s.Expires}
+	assert.NilError(t, snippets.Update(edit))
+	assert.NilError(t, snippets.Delete(s.ID))
+	due, err = m.Due(10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(due), 2)
+	assert.Equal(t, due[0].Event, EventSnippetUpdated)
+	payload = WebhookPayload{}
+	assert.NilError(t, json.Unmarshal(due[0].Payload, &payload))
+	assert.Equal(t, payload.Snippet.Slug, s.Slug)
+	assert.Equal(t, payload.Snippet.Title, "Hooked again")
+	assert.Equal(t, payload.Snippet.Created.Equal(s.Created), true)
+	assert.Equal(t, due[1].Event, EventSnippetDeleted)
+
+	// Alice's expired snippet from the test data.
+	_, err = snippets.DeleteExpired(10)
+	assert.NilError(t, err)
+	due, err = m.Due(10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(due), 3)
+	assert.Equal(t, due[2].Event, EventSnippetExpired)
+
+	deliveries, err := m.Deliveries(1, 10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(deliveries), 4)
+	assert.Equal(t, deliveries[3].Status, DeliveryDelivered)
+	deliveries, err = m.Deliveries(2, 10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(deliveries), 0)
+
+	assert.Equal(t, errors.Is(m.Delete(webhook.ID, 2), ErrNoRecord), true)
+	assert.NilError(t, m.Delete(webhook.ID, 1))
+	deliveries, err = m.Deliveries(1, 10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(deliveries), 0)
+}
@@ -0,0 +1,81 @@
+// Package ratelimit provides an in-memory, fixed-window rate limiter keyed by
+// arbitrary strings such as client IPs or snippet IDs.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type window struct {
+	count int
+	reset time.Time
+}
+
+// Limiter allows up to limit events per key within each window. It is safe
+// for concurrent use.
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+func New(limit int, w time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  w,
+		now:     time.Now,
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.reset) {
+		w = &window{reset: now.Add(l.window)}
+		l.windows[key] = w
+	}
+	w.count++
+	return w.count <= l.limit
+}
+
+// Exceeded reports whether key has used up its budget for the current
+// window, without recording an event.
+func (l *Limiter) Exceeded(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	return ok && l.now().Before(w.reset) && w.count >= l.limit
+}
+
+// Reset forgets every event recorded for key.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.windows, key)
+}
+
+// sweep drops expired windows so that keys which are never seen again don't
+// accumulate. It runs at most once per window.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, w := range l.windows {
+		if !now.Before(w.reset) {
+			delete(l.windows, key)
+		}
+	}
+}
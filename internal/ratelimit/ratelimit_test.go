@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	assert.Equal(t, l.Allow("a"), true)
+	assert.Equal(t, l.Exceeded("a"), false)
+	assert.Equal(t, l.Allow("a"), true)
+	assert.Equal(t, l.Exceeded("a"), true)
+	assert.Equal(t, l.Allow("a"), false)
+	// Other keys have their own budget.
+	assert.Equal(t, l.Allow("b"), true)
+	assert.Equal(t, l.Exceeded("c"), false)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, l.Exceeded("a"), false)
+	assert.Equal(t, l.Allow("a"), true)
+
+	l.Reset("a")
+	assert.Equal(t, l.Allow("a"), true)
+	assert.Equal(t, l.Allow("a"), true)
+	assert.Equal(t, l.Allow("a"), false)
+}
+
+func TestLimiterSweep(t *testing.T) {
+	now := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	l := New(1, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("b")
+	now = now.Add(2 * time.Minute)
+	l.Allow("c")
+	assert.Equal(t, len(l.windows), 1)
+}
@@ -0,0 +1,223 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// titleBoost is how much more a term counts in a title than in content.
+const titleBoost = 2
+
+// span is the range of word positions a token covers in a document.
+type span struct {
+	start, end int
+}
+
+// posting records where a term occurs in one document.
+type posting struct {
+	spans []span
+	// title is how many of the spans are in the title.
+	title int
+}
+
+// Memory is an in-process inverted index. It is safe for concurrent use.
+type Memory struct {
+	mu       sync.RWMutex
+	postings map[string]map[int]*posting
+	// docs holds the terms of every document so that it can be removed.
+	docs map[int][]string
+	// terms is the sorted list of all terms used by prefix queries. It is
+	// rebuilt lazily after the index changed.
+	terms []string
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		postings: make(map[string]map[int]*posting),
+		docs:     make(map[int][]string),
+	}
+}
+
+func (m *Memory) Add(doc Document) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(doc.ID)
+
+	title := Tokenize(doc.Title)
+	// Content positions start after a gap so that phrases don't run from
+	// the title into the content.
+	offset := 1
+	if len(title) > 0 {
+		offset = title[len(title)-1].End + 2
+	}
+	var terms []string
+	add := func(t Token, inTitle bool) {
+		docs, ok := m.postings[t.Text]
+		if !ok {
+			docs = make(map[int]*posting)
+			m.postings[t.Text] = docs
+			m.terms = nil
+		}
+		p, ok := docs[doc.ID]
+		if !ok {
+			p = &posting{}
+			docs[doc.ID] = p
+			terms = append(terms, t.Text)
+		}
+		p.spans = append(p.spans, span{t.Start, t.End})
+		if inTitle {
+			p.title++
+		}
+	}
+	for _, t := range title {
+		add(t, true)
+	}
+	for _, t := range Tokenize(doc.Content) {
+		t.Start += offset
+		t.End += offset
+		add(t, false)
+	}
+	m.docs[doc.ID] = terms
+}
+
+func (m *Memory) Remove(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(id)
+}
+
+func (m *Memory) remove(id int) {
+	for _, term := range m.docs[id] {
+		delete(m.postings[term], id)
+		if len(m.postings[term]) == 0 {
+			delete(m.postings, term)
+			m.terms = nil
+		}
+	}
+	delete(m.docs, id)
+}
+
+// Len returns the number of documents in the index.
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.docs)
+}
+
+// Search returns the IDs of the documents matching every clause of query.
+// Documents are scored by how often the terms occur in them, weighted by
+// how rare the terms are, with title matches counting more.
+func (m *Memory) Search(query string) []int {
+	clauses := parseQuery(query)
+	if len(clauses) == 0 {
+		return []int{}
+	}
+
+	m.mu.Lock()
+	if m.terms == nil {
+		m.terms = make([]string, 0, len(m.postings))
+		for term := range m.postings {
+			m.terms = append(m.terms, term)
+		}
+		sort.Strings(m.terms)
+	}
+	// m.terms is only ever replaced, never modified, so the snapshot stays
+	// valid once the lock is released.
+	terms := m.terms
+	m.mu.Unlock()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var scores map[int]float64
+	for _, c := range clauses {
+		matches := m.match(c, terms)
+		if scores == nil {
+			scores = matches
+			continue
+		}
+		for id := range scores {
+			if s, ok := matches[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	ids := make([]int, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] > ids[j]
+	})
+	return ids
+}
+
+// match scores the documents matching a clause. terms is the sorted list of
+// indexed terms used to expand a prefix.
+func (m *Memory) match(c clause, terms []string) map[int]float64 {
+	alternatives := make([][]string, len(c.terms))
+	for i, term := range c.terms {
+		alternatives[i] = []string{term}
+	}
+	if c.prefix {
+		alternatives[len(c.terms)-1] = withPrefix(terms, c.terms[len(c.terms)-1])
+	}
+
+	scores := make(map[int]float64)
+	for _, term := range alternatives[0] {
+		idf := m.idf(term)
+		for id, p := range m.postings[term] {
+			if len(alternatives) == 1 {
+				scores[id] += float64(len(p.spans)+titleBoost*p.title) * idf
+				continue
+			}
+			for _, sp := range p.spans {
+				if m.follows(id, sp.end, alternatives[1:]) {
+					scores[id] += idf
+				}
+			}
+		}
+	}
+	return scores
+}
+
+// follows reports whether the terms of a phrase occur in document id right
+// after position end.
+func (m *Memory) follows(id, end int, alternatives [][]string) bool {
+	if len(alternatives) == 0 {
+		return true
+	}
+	for _, term := range alternatives[0] {
+		p, ok := m.postings[term][id]
+		if !ok {
+			continue
+		}
+		for _, sp := range p.spans {
+			if sp.start == end+1 && m.follows(id, sp.end, alternatives[1:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withPrefix returns the terms starting with prefix from a sorted list.
+func withPrefix(terms []string, prefix string) []string {
+	i := sort.SearchStrings(terms, prefix)
+	j := i
+	for j < len(terms) && strings.HasPrefix(terms[j], prefix) {
+		j++
+	}
+	return terms[i:j]
+}
+
+func (m *Memory) idf(term string) float64 {
+	return math.Log(1 + float64(len(m.docs))/float64(len(m.postings[term])))
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func newTestIndex() *Memory {
+	m := NewMemory()
+	m.Add(Document{ID: 1, Title: "Hello handler", Content: "http.HandleFunc(\"/\", home)\nvar h http.HandlerFunc = ping"})
+	m.Add(Document{ID: 2, Title: "Parsing config", Content: "func parseConfig(path string) (*Config, error)\nconst max_body_size = 1 << 20"})
+	m.Add(Document{ID: 3, Title: "An old silent pond", Content: "A frog jumps into the pond, splash! Silence again."})
+	m.Add(Document{ID: 4, Title: "Middleware", Content: "func secureHeaders(next http.Handler) http.Handler"})
+	return m
+}
+
+func TestMemorySearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{name: "Word", query: "pond", want: []int{3}},
+		{name: "Case insensitive", query: "POND", want: []int{3}},
+		{name: "All words must match", query: "silent frog", want: []int{3}},
+		{name: "Missing word", query: "silent toad", want: []int{}},
+		{name: "Whole identifier", query: "HandlerFunc", want: []int{1}},
+		{name: "Camel case part", query: "config", want: []int{2}},
+		{name: "Snake case", query: "max_body_size", want: []int{2}},
+		{name: "Snake case part", query: "body", want: []int{2}},
+		{name: "Dotted identifier", query: "http.HandlerFunc", want: []int{1}},
+		{name: "Dotted identifier by parts", query: "http.Handler", want: []int{4, 1}},
+		{name: "Title ranks higher", query: "handler", want: []int{1, 4}},
+		{name: "Phrase", query: `"silent pond"`, want: []int{3}},
+		{name: "Phrase out of order", query: `"pond silent"`, want: []int{}},
+		{name: "Phrase across title and content", query: `"pond a frog"`, want: []int{}},
+		{name: "Phrase of identifier parts", query: `"parse config"`, want: []int{2}},
+		{name: "Prefix", query: "sil*", want: []int{3}},
+		{name: "Prefix of a part", query: "secure*", want: []int{4}},
+		{name: "Prefix in dotted identifier", query: "http.HandleF*", want: []int{1}},
+		{name: "Unknown prefix", query: "zzz*", want: []int{}},
+		{name: "No terms", query: `"" * .`, want: []int{}},
+	}
+	m := newTestIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, fmt.Sprint(m.Search(tt.query)), fmt.Sprint(tt.want))
+		})
+	}
+}
+
+func TestMemoryAddRemove(t *testing.T) {
+	m := newTestIndex()
+	assert.Equal(t, m.Len(), 4)
+
+	// Adding a document again replaces it.
+	m.Add(Document{ID: 3, Title: "Over the wintry forest"})
+	assert.Equal(t, m.Len(), 4)
+	assert.Equal(t, fmt.Sprint(m.Search("pond")), "[]")
+	assert.Equal(t, fmt.Sprint(m.Search("wintry")), "[3]")
+	assert.Equal(t, fmt.Sprint(m.Search("win*")), "[3]")
+
+	m.Remove(3)
+	m.Remove(99)
+	assert.Equal(t, m.Len(), 3)
+	assert.Equal(t, fmt.Sprint(m.Search("win*")), "[]")
+	_, ok := m.postings["wintry"]
+	assert.Equal(t, ok, false)
+}
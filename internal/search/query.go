@@ -0,0 +1,53 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// clause is one part of a query that documents have to match. A clause with
+// a single term matches documents containing it; longer clauses are phrases
+// whose terms have to follow each other.
+type clause struct {
+	terms []string
+	// prefix makes the last term match every term starting with it.
+	prefix bool
+}
+
+// parseQuery splits a query into clauses. Text in double quotes is a
+// phrase, as is a single word that tokenizes into several terms such as
+// http.HandlerFunc. A trailing * turns the last term into a prefix.
+func parseQuery(q string) []clause {
+	var clauses []clause
+	for len(q) > 0 {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			break
+		}
+		var text string
+		if q[0] == '"' {
+			end := strings.IndexByte(q[1:], '"')
+			if end < 0 {
+				text, q = q[1:], ""
+			} else {
+				text, q = q[1:end+1], q[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			if end < 0 {
+				end = len(q)
+			}
+			text, q = q[:end], q[end:]
+		}
+		c := clause{prefix: strings.HasSuffix(text, "*")}
+		for _, t := range Tokenize(strings.TrimSuffix(text, "*")) {
+			if !t.Part {
+				c.terms = append(c.terms, t.Text)
+			}
+		}
+		if len(c.terms) > 0 {
+			clauses = append(clauses, c)
+		}
+	}
+	return clauses
+}
@@ -0,0 +1,23 @@
+// Package search indexes snippets in process so that they can be searched
+// without relying on MySQL's full-text search, which splits code like
+// http.HandlerFunc or snake_case poorly.
+package search
+
+// Document is the searchable text of a snippet.
+type Document struct {
+	ID      int
+	Title   string
+	Content string
+}
+
+// Index finds documents matching a query. Queries are made of words that
+// all have to match; "quoted text" matches a phrase and a trailing * a
+// prefix. Words are split like identifiers (see Tokenize), so a query for
+// handler finds HandlerFunc and http.HandlerFunc is matched as a phrase.
+type Index interface {
+	// Add indexes doc, replacing any document with the same ID.
+	Add(doc Document)
+	Remove(id int)
+	// Search returns the IDs of matching documents, best match first.
+	Search(query string) []int
+}
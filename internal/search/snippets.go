@@ -0,0 +1,109 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/xyedo/snippetbox/internal/models"
+)
+
+// maxHits caps how many matches a search looks up in the database.
+const maxHits = 1000
+
+// Snippets wraps a snippet model so that every change to a snippet also
+// updates Index, and answers searches from Index instead of the database.
+type Snippets struct {
+	models.SnippetModelInterface
+	Index Index
+}
+
+// document returns what is indexed of a snippet. The ciphertext of
+// encrypted snippets is meaningless, so only their title is.
+func document(s *models.Snippet) Document {
+	doc := Document{ID: s.ID, Title: s.Title, Content: s.Content}
+	if s.Encrypted {
+		doc.Content = ""
+	}
+	return doc
+}
+
+// Rebuild indexes every snippet in the database and returns how many there
+// were.
+func (m *Snippets) Rebuild() (int, error) {
+	n := 0
+	err := m.SnippetModelInterface.Each(func(s *models.Snippet) error {
+		m.Index.Add(document(s))
+		n++
+		return nil
+	})
+	return n, err
+}
+
+func (m *Snippets) Insert(s *models.Snippet, password string) error {
+	if err := m.SnippetModelInterface.Insert(s, password); err != nil {
+		return err
+	}
+	m.Index.Add(document(s))
+	return nil
+}
+
+func (m *Snippets) Update(s *models.Snippet) error {
+	if err := m.SnippetModelInterface.Update(s); err != nil {
+		return err
+	}
+	m.Index.Add(document(s))
+	return nil
+}
+
+func (m *Snippets) Delete(id int) error {
+	if err := m.SnippetModelInterface.Delete(id); err != nil {
+		return err
+	}
+	m.Index.Remove(id)
+	return nil
+}
+
+func (m *Snippets) Consume(id int) (*models.Snippet, error) {
+	s, err := m.SnippetModelInterface.Consume(id)
+	if err != nil {
+		return nil, err
+	}
+	m.Index.Remove(id)
+	return s, nil
+}
+
+func (m *Snippets) DeleteExpired(limit int) ([]int, error) {
+	ids, err := m.SnippetModelInterface.DeleteExpired(limit)
+	for _, id := range ids {
+		m.Index.Remove(id)
+	}
+	return ids, err
+}
+
+// Search looks query up in the index and then loads the matches userID may
+// find from the database, which also drops snippets that expired since they
+// were indexed.
+func (m *Snippets) Search(query string, userID, offset, limit int) ([]*models.Snippet, error) {
+	ids := m.Index.Search(query)
+	if len(ids) > maxHits {
+		ids = ids[:maxHits]
+	}
+	snippets, err := m.SnippetModelInterface.Searchable(ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	rank := make(map[int]int, len(ids))
+	for i, id := range ids {
+		rank[id] = i
+	}
+	sort.Slice(snippets, func(i, j int) bool {
+		return rank[snippets[i].ID] < rank[snippets[j].ID]
+	})
+	if offset > len(snippets) {
+		offset = len(snippets)
+	}
+	snippets = snippets[offset:]
+	if limit < len(snippets) {
+		snippets = snippets[:limit]
+	}
+	return snippets, nil
+}
@@ -0,0 +1,63 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+	"github.com/xyedo/snippetbox/internal/models"
+	"github.com/xyedo/snippetbox/internal/models/mock"
+)
+
+func TestSnippets(t *testing.T) {
+	idx := NewMemory()
+	m := &Snippets{SnippetModelInterface: &mock.SnippetModel{}, Index: idx}
+	n, err := m.Rebuild()
+	assert.NilError(t, err)
+	assert.Equal(t, n, idx.Len())
+
+	t.Run("Search", func(t *testing.T) {
+		// The private diary is only found by its owner.
+		snippets, err := m.Search("diary", 2, 0, 10)
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 0)
+		snippets, err = m.Search("diary", 1, 0, 10)
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 1)
+
+		// Results keep the order of the index.
+		want := fmt.Sprint(idx.Search("the"))
+		var got []int
+		for offset := 0; ; offset++ {
+			snippets, err := m.Search("the", 2, offset, 1)
+			assert.NilError(t, err)
+			if len(snippets) == 0 {
+				break
+			}
+			got = append(got, snippets[0].ID)
+		}
+		assert.Equal(t, fmt.Sprint(got), want)
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		s := &models.Snippet{Title: "Gopher", Content: "func (app *application) routes() http.Handler"}
+		assert.NilError(t, m.Insert(s, ""))
+		assert.Equal(t, fmt.Sprint(idx.Search(`"application routes"`)), fmt.Sprint([]int{s.ID}))
+
+		assert.NilError(t, m.Update(&models.Snippet{ID: 3, Title: "Over the wintry forest", Content: "nothing left"}))
+		assert.Equal(t, fmt.Sprint(idx.Search("howl")), "[]")
+		assert.Equal(t, fmt.Sprint(idx.Search("nothing")), "[3]")
+
+		// Encrypted content is ciphertext, so only the title is indexed.
+		assert.NilError(t, m.Update(&models.Snippet{ID: 3, Title: "Secret", Content: "c2VjcmV0", Encrypted: true}))
+		assert.Equal(t, fmt.Sprint(idx.Search("secret")), "[3]")
+		assert.Equal(t, fmt.Sprint(idx.Search("c2VjcmV0")), "[]")
+
+		assert.NilError(t, m.Delete(1))
+		assert.Equal(t, fmt.Sprint(idx.Search("silent")), "[]")
+
+		_, err := m.Consume(5)
+		assert.NilError(t, err)
+		assert.Equal(t, fmt.Sprint(idx.Search("door")), "[]")
+	})
+}
@@ -0,0 +1,74 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token is a lowercased term found in a text. Start and End are the
+// positions of the first and last word the token covers.
+//
+// Identifiers made of several words, like HandlerFunc or snake_case, yield a
+// token for the whole identifier spanning all of its words, followed by a
+// Part token for every word. Everything that isn't a letter, digit or
+// underscore separates identifiers, so http.HandlerFunc is "http" followed
+// by "handlerfunc" (with the parts "handler" and "func").
+type Token struct {
+	Text  string
+	Start int
+	End   int
+	Part  bool
+}
+
+// Tokenize splits text into tokens with positions starting at 0.
+func Tokenize(text string) []Token {
+	var tokens []Token
+	pos := 0
+	for _, word := range strings.FieldsFunc(text, isSeparator) {
+		parts := splitIdentifier(word)
+		if len(parts) == 0 {
+			continue
+		}
+		whole := strings.ToLower(word)
+		if len(parts) == 1 && parts[0] == whole {
+			tokens = append(tokens, Token{Text: whole, Start: pos, End: pos})
+			pos++
+			continue
+		}
+		tokens = append(tokens, Token{Text: whole, Start: pos, End: pos + len(parts) - 1})
+		for i, p := range parts {
+			tokens = append(tokens, Token{Text: p, Start: pos + i, End: pos + i, Part: true})
+		}
+		pos += len(parts)
+	}
+	return tokens
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+// splitIdentifier splits an identifier into its lowercased words at
+// underscores and camelCase boundaries. Digits stick to the word before
+// them, and runs of capitals are kept together as in ServeHTTP or
+// HTTPServer.
+func splitIdentifier(word string) []string {
+	var parts []string
+	for _, piece := range strings.Split(word, "_") {
+		runes := []rune(piece)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			lowerToUpper := (unicode.IsLower(prev) || unicode.IsDigit(prev)) && unicode.IsUpper(cur)
+			endOfCapitals := unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if lowerToUpper || endOfCapitals {
+				parts = append(parts, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, strings.ToLower(string(runes[start:])))
+		}
+	}
+	return parts
+}
@@ -0,0 +1,44 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "Words", text: "An old, silent pond", want: "an@0 old@1 silent@2 pond@3"},
+		{name: "Dots", text: "http.HandlerFunc(f)", want: "http@0 handlerfunc@1-2 handler*@1 func*@2 f@3"},
+		{name: "Snake case", text: "max_body_size", want: "max_body_size@0-2 max*@0 body*@1 size*@2"},
+		{name: "Capitals", text: "ServeHTTP HTTPServer", want: "servehttp@0-1 serve*@0 http*@1 httpserver@2-3 http*@2 server*@3"},
+		{name: "Digits", text: "base64 utf8Decode", want: "base64@0 utf8decode@1-2 utf8*@1 decode*@2"},
+		{name: "Leading underscore", text: "__init__", want: "__init__@0 init*@0"},
+		{name: "Underscores only", text: "a ___ b", want: "a@0 b@1"},
+		{name: "Unicode", text: "Größe übersetzen", want: "größe@0 übersetzen@1"},
+		{name: "Empty", text: " .,; ", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tok := range Tokenize(tt.text) {
+				s := tok.Text
+				if tok.Part {
+					s += "*"
+				}
+				s += fmt.Sprintf("@%d", tok.Start)
+				if tok.End != tok.Start {
+					s += fmt.Sprintf("-%d", tok.End)
+				}
+				got = append(got, s)
+			}
+			assert.Equal(t, strings.Join(got, " "), tt.want)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package syntax
+
+import "strings"
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var languages = map[string]*Language{
+	"go": {
+		Name:      "go",
+		Label:     "Go",
+		Extension: ".go",
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var`),
+		builtins: words(`any bool byte comparable complex64 complex128 error float32 float64 int int8 int16
+			int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr true false nil iota
+			append cap close complex copy delete imag len make new panic print println real recover`),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes: []quote{
+			{open: `"`, close: `"`, escapes: true},
+			{open: `'`, close: `'`, escapes: true},
+			{open: "`", close: "`", multiline: true},
+		},
+	},
+	"python": {
+		Name:      "python",
+		Label:     "Python",
+		Extension: ".py",
+		keywords: words(`False None True and as assert async await break class continue def del elif else
+			except finally for from global if import in is lambda nonlocal not or pass raise return
+			try while with yield`),
+		builtins: words(`abs all any bool bytes dict enumerate filter float getattr hasattr int isinstance
+			len list map max min object open print range repr set setattr sorted str sum super tuple
+			type zip self`),
+		lineComments: []string{"#"},
+		quotes: []quote{
+			{open: `"""`, close: `"""`, escapes: true, multiline: true},
+			{open: `'''`, close: `'''`, escapes: true, multiline: true},
+			{open: `"`, close: `"`, escapes: true},
+			{open: `'`, close: `'`, escapes: true},
+		},
+	},
+	"javascript": {
+		Name:      "javascript",
+		Label:     "JavaScript",
+		Extension: ".js",
+		keywords: words(`async await break case catch class const continue debugger default delete do else
+			export extends finally for function if import in instanceof let new of return static super
+			switch this throw try typeof var void while with yield`),
+		builtins: words(`true false null undefined NaN Infinity Array Boolean Date Error JSON Map Math
+			Number Object Promise RegExp Set String Symbol console document window`),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes: []quote{
+			{open: `"`, close: `"`, escapes: true},
+			{open: `'`, close: `'`, escapes: true},
+			{open: "`", close: "`", escapes: true, multiline: true},
+		},
+		identChars: "$",
+	},
+	"sql": {
+		Name:      "sql",
+		Label:     "SQL",
+		Extension: ".sql",
+		keywords: words(`add all alter and as asc begin between by case check commit constraint create
+			cross default delete desc distinct drop else end exists foreign from full group having if
+			in index inner insert into is join key left like limit not null offset on or order outer
+			primary references right rollback select set table then transaction union unique update
+			using values view when where with`),
+		builtins: words(`avg bigint blob boolean char coalesce count date datetime decimal double float int
+			integer max min now text timestamp varchar sum true false`),
+		foldCase:      true,
+		lineComments:  []string{"--", "#"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes: []quote{
+			{open: `'`, close: `'`, escapes: true},
+			{open: `"`, close: `"`, escapes: true},
+			{open: "`", close: "`"},
+		},
+	},
+	"shell": {
+		Name:      "shell",
+		Label:     "Shell",
+		Extension: ".sh",
+		keywords: words(`case do done elif else esac fi for function if in local return select then until
+			while export`),
+		builtins:     words(`cd echo eval exec exit printf read set shift source test trap unset`),
+		lineComments: []string{"#"},
+		quotes: []quote{
+			{open: `"`, close: `"`, escapes: true, multiline: true},
+			{open: `'`, close: `'`, multiline: true},
+		},
+		identChars: "-",
+	},
+}
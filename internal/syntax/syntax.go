@@ -0,0 +1,221 @@
+// Package syntax splits source code into tokens for syntax highlighting.
+//
+// It doesn't produce HTML: templates wrap each token in an element whose
+// class is the token's Kind, so the code itself is escaped by html/template
+// like any other user content.
+package syntax
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind is the kind of a token. Plain text has the empty Kind.
+type Kind string
+
+const (
+	Text    Kind = ""
+	Keyword Kind = "keyword"
+	Builtin Kind = "builtin"
+	String  Kind = "string"
+	Number  Kind = "number"
+	Comment Kind = "comment"
+)
+
+type Token struct {
+	Kind Kind
+	Text string
+}
+
+// quote describes a kind of string literal.
+type quote struct {
+	open, close string
+	// escapes makes a backslash escape the character after it.
+	escapes bool
+	// multiline strings may contain newlines.
+	multiline bool
+}
+
+// Language holds what the lexer needs to know about a language.
+type Language struct {
+	// Name is what is stored with snippets, Label what users see.
+	Name  string
+	Label string
+	// Extension is the usual file name extension, with the dot.
+	Extension string
+
+	keywords     map[string]bool
+	builtins     map[string]bool
+	foldCase     bool
+	lineComments []string
+	// blockComments are pairs of opening and closing delimiters.
+	blockComments [][2]string
+	// quotes are tried in order, so longer openings go first.
+	quotes []quote
+	// identChars are allowed in identifiers besides letters, digits and
+	// underscores.
+	identChars string
+}
+
+var numberRX = regexp.MustCompile(`^(?:0[xX][0-9a-fA-F_]+|[0-9][0-9_]*(?:\.[0-9_]+)?(?:[eE][+-]?[0-9]+)?)`)
+
+// Languages returns the supported languages sorted by label.
+func Languages() []*Language {
+	sorted := make([]*Language, 0, len(languages))
+	for _, l := range languages {
+		sorted = append(sorted, l)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Label < sorted[j].Label
+	})
+	return sorted
+}
+
+// Names returns the names of the supported languages, plus the empty name
+// of plain text.
+func Names() []string {
+	names := []string{""}
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the language called name.
+func Lookup(name string) (*Language, bool) {
+	l, ok := languages[name]
+	return l, ok
+}
+
+// Tokenize splits src into tokens of the language called name. Unknown
+// languages, including plain text, give a single Text token. Concatenating
+// the tokens always gives back src.
+func Tokenize(name, src string) []Token {
+	l, ok := languages[name]
+	if !ok {
+		if src == "" {
+			return nil
+		}
+		return []Token{{Text, src}}
+	}
+	return l.tokenize(src)
+}
+
+func (l *Language) tokenize(src string) []Token {
+	var tokens []Token
+	emit := func(kind Kind, text string) {
+		if n := len(tokens); n > 0 && tokens[n-1].Kind == kind {
+			tokens[n-1].Text += text
+			return
+		}
+		tokens = append(tokens, Token{kind, text})
+	}
+
+	prevIdent := false
+	for i := 0; i < len(src); {
+		rest := src[i:]
+		if n := l.comment(rest); n > 0 {
+			emit(Comment, rest[:n])
+			i += n
+			prevIdent = false
+			continue
+		}
+		if n := l.string(rest); n > 0 {
+			emit(String, rest[:n])
+			i += n
+			prevIdent = false
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(rest)
+		if !prevIdent && unicode.IsDigit(r) {
+			if m := numberRX.FindString(rest); m != "" {
+				emit(Number, m)
+				i += len(m)
+				continue
+			}
+		}
+		if l.isIdent(r) && !unicode.IsDigit(r) && !prevIdent {
+			n := strings.IndexFunc(rest, func(r rune) bool { return !l.isIdent(r) })
+			if n < 0 {
+				n = len(rest)
+			}
+			word := rest[:n]
+			key := word
+			if l.foldCase {
+				key = strings.ToLower(word)
+			}
+			switch {
+			case l.keywords[key]:
+				emit(Keyword, word)
+			case l.builtins[key]:
+				emit(Builtin, word)
+			default:
+				emit(Text, word)
+			}
+			i += n
+			prevIdent = true
+			continue
+		}
+		emit(Text, rest[:size])
+		i += size
+		prevIdent = l.isIdent(r)
+	}
+	return tokens
+}
+
+func (l *Language) isIdent(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || strings.ContainsRune(l.identChars, r)
+}
+
+// comment returns the length of the comment src starts with, or 0. An
+// unterminated block comment runs to the end of src.
+func (l *Language) comment(src string) int {
+	for _, prefix := range l.lineComments {
+		if strings.HasPrefix(src, prefix) {
+			if n := strings.IndexByte(src, '\n'); n >= 0 {
+				return n
+			}
+			return len(src)
+		}
+	}
+	for _, delims := range l.blockComments {
+		if strings.HasPrefix(src, delims[0]) {
+			if n := strings.Index(src[len(delims[0]):], delims[1]); n >= 0 {
+				return len(delims[0]) + n + len(delims[1])
+			}
+			return len(src)
+		}
+	}
+	return 0
+}
+
+// string returns the length of the string literal src starts with, or 0.
+// An unterminated literal runs to the end of the line, or of src for
+// multiline literals.
+func (l *Language) string(src string) int {
+	for _, q := range l.quotes {
+		if !strings.HasPrefix(src, q.open) {
+			continue
+		}
+		i := len(q.open)
+		for i < len(src) {
+			switch {
+			case strings.HasPrefix(src[i:], q.close):
+				return i + len(q.close)
+			case src[i] == '\n' && !q.multiline:
+				return i
+			case src[i] == '\\' && q.escapes && i+1 < len(src):
+				_, size := utf8.DecodeRuneInString(src[i+1:])
+				i += 1 + size
+			default:
+				i++
+			}
+		}
+		return len(src)
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package syntax
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xyedo/snippetbox/internal/assert"
+)
+
+// format writes tokens as kind:text, leaving out the kind of plain text.
+func format(tokens []Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString("[")
+		if t.Kind != Text {
+			b.WriteString(string(t.Kind) + ":")
+		}
+		b.WriteString(t.Text + "]")
+	}
+	return b.String()
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		src  string
+		want string
+	}{
+		{
+			name: "Plain text",
+			lang: "",
+			src:  "func main() {}",
+			want: "[func main() {}]",
+		},
+		{
+			name: "Unknown language",
+			lang: "cobol",
+			src:  "DISPLAY 'HI'",
+			want: "[DISPLAY 'HI']",
+		},
+		{
+			name: "Go",
+			lang: "go",
+			src:  "func f(n int) string { return \"a\\\"b\" } // done",
+			want: `[keyword:func][ f(n ][builtin:int][) ][builtin:string][ { ][keyword:return][ ][string:"a\"b"][ } ][comment:// done]`,
+		},
+		{
+			name: "Go raw string",
+			lang: "go",
+			src:  "x := `a\nb` + 0x1F",
+			want: "[x := ][string:`a\nb`][ + ][number:0x1F]",
+		},
+		{
+			name: "Keywords inside identifiers",
+			lang: "go",
+			src:  "format x2 ifx",
+			want: "[format x2 ifx]",
+		},
+		{
+			name: "Block comment",
+			lang: "javascript",
+			src:  "/* a\nb */ let $x = 1.5e3;",
+			want: "[comment:/* a\nb */][ ][keyword:let][ $x = ][number:1.5e3][;]",
+		},
+		{
+			name: "Unterminated string stops at the line end",
+			lang: "python",
+			src:  "s = 'abc\nprint(s)",
+			want: "[s = ][string:'abc][\n][builtin:print][(s)]",
+		},
+		{
+			// Adjacent tokens of the same kind are merged.
+			name: "Triple quotes",
+			lang: "python",
+			src:  "\"\"\"doc\n\"string\"\"\"\"\" # note",
+			want: "[string:\"\"\"doc\n\"string\"\"\"\"\"][ ][comment:# note]",
+		},
+		{
+			name: "SQL ignores case",
+			lang: "sql",
+			src:  "Select COUNT(*) from t -- all",
+			want: "[keyword:Select][ ][builtin:COUNT][(*) ][keyword:from][ t ][comment:-- all]",
+		},
+		{
+			name: "HTML is left alone",
+			lang: "shell",
+			src:  "echo '<script>'",
+			want: "[builtin:echo][ ][string:'<script>']",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := Tokenize(tt.lang, tt.src)
+			assert.Equal(t, format(tokens), tt.want)
+
+			var b strings.Builder
+			for _, tok := range tokens {
+				b.WriteString(tok.Text)
+			}
+			assert.Equal(t, b.String(), tt.src)
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	names := Names()
+	assert.Equal(t, names[0], "")
+	assert.Equal(t, len(names), len(Languages())+1)
+	for _, name := range names[1:] {
+		l, ok := Lookup(name)
+		assert.Equal(t, ok, true)
+		assert.Equal(t, l.Name, name)
+	}
+}
@@ -8,6 +8,10 @@ import (
 
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// TagRX matches tag names: up to 32 lowercase letters, digits, dots and
+// dashes, starting and ending with a letter or digit.
+var TagRX = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9.-]{0,30}[a-z0-9])?$`)
+
 type Validator struct {
 	NonFieldErrors []string
 	FieldErrors    map[string]string
@@ -53,3 +57,14 @@ func MinChars(value string, n int) bool {
 func Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
 }
+func MaxItems[T any](values []T, n int) bool {
+	return len(values) <= n
+}
+func AllMatch(values []string, rx *regexp.Regexp) bool {
+	for _, v := range values {
+		if !rx.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
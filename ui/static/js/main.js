@@ -5,4 +5,144 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+
+// Snippets created with "Encrypt in my browser" are encrypted with AES-GCM
+// before they are submitted. The key only ever lives in the URL fragment,
+// which browsers never send to the server.
+var snippetCrypto = {
+	encode: function (bytes) {
+		var s = "";
+		for (var i = 0; i < bytes.length; i++) {
+			s += String.fromCharCode(bytes[i]);
+		}
+		return btoa(s).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+	},
+	decode: function (s) {
+		s = s.replace(/-/g, "+").replace(/_/g, "/");
+		while (s.length % 4) {
+			s += "=";
+		}
+		var bin = atob(s);
+		var bytes = new Uint8Array(bin.length);
+		for (var i = 0; i < bin.length; i++) {
+			bytes[i] = bin.charCodeAt(i);
+		}
+		return bytes;
+	},
+	importKey: function (encoded) {
+		return Promise.resolve().then(function () {
+			return crypto.subtle.importKey("raw", snippetCrypto.decode(encoded), "AES-GCM", false, ["encrypt", "decrypt"]);
+		});
+	},
+	// encrypt returns base64url(iv || ciphertext), the format the server
+	// checks for with models.IsCiphertext.
+	encrypt: function (key, text) {
+		var iv = crypto.getRandomValues(new Uint8Array(12));
+		return crypto.subtle.encrypt({name: "AES-GCM", iv: iv}, key, new TextEncoder().encode(text)).then(function (ciphertext) {
+			var out = new Uint8Array(iv.length + ciphertext.byteLength);
+			out.set(iv);
+			out.set(new Uint8Array(ciphertext), iv.length);
+			return snippetCrypto.encode(out);
+		});
+	},
+	decrypt: function (key, payload) {
+		return Promise.resolve().then(function () {
+			var bytes = snippetCrypto.decode(payload);
+			return crypto.subtle.decrypt({name: "AES-GCM", iv: bytes.slice(0, 12)}, key, bytes.slice(12));
+		}).then(function (plaintext) {
+			return new TextDecoder().decode(plaintext);
+		});
+	}
+};
+
+// Forms that post back to the snippet's own URL (unlocking it, or reading a
+// burn-after-reading snippet) have to carry the key along.
+if (window.location.hash) {
+	var forms = document.querySelectorAll("form");
+	for (var i = 0; i < forms.length; i++) {
+		var action = forms[i].getAttribute("action");
+		if (action.indexOf(window.location.pathname) == 0) {
+			forms[i].setAttribute("action", action + window.location.hash);
+		}
+	}
+}
+
+var createForm = document.querySelector("form[action^='/snippet/create']");
+if (createForm) {
+	var encryptBox = createForm.querySelector("input[name='encrypted']");
+	var contentField = createForm.querySelector("textarea[name='content']");
+	// When validation fails the form comes back with the ciphertext, which
+	// can still be decrypted with the key in the fragment.
+	if (encryptBox.checked && window.location.hash && contentField.value) {
+		snippetCrypto.importKey(window.location.hash.slice(1)).then(function (key) {
+			return snippetCrypto.decrypt(key, contentField.value);
+		}).then(function (text) {
+			contentField.value = text;
+		}, function () {
+			contentField.value = "";
+		});
+	}
+	createForm.addEventListener("submit", function (e) {
+		if (!encryptBox.checked || contentField.value == "") {
+			return;
+		}
+		e.preventDefault();
+		var rawKey = snippetCrypto.encode(crypto.getRandomValues(new Uint8Array(32)));
+		snippetCrypto.importKey(rawKey).then(function (key) {
+			return snippetCrypto.encrypt(key, contentField.value);
+		}).then(function (payload) {
+			contentField.value = payload;
+			// The redirect to the new snippet keeps the fragment, so the key
+			// ends up in its URL.
+			createForm.setAttribute("action", "/snippet/create#" + rawKey);
+			createForm.submit();
+		});
+	});
+}
+
+var encryptedContent = document.querySelector("code[data-ciphertext]");
+if (encryptedContent) {
+	snippetCrypto.importKey(window.location.hash.slice(1)).then(function (key) {
+		return snippetCrypto.decrypt(key, encryptedContent.getAttribute("data-ciphertext"));
+	}).then(function (text) {
+		encryptedContent.textContent = text;
+	}, function () {
+		encryptedContent.textContent = "This snippet can't be decrypted. Make sure you opened the complete link, including everything after the #.";
+	});
+}
+
+// Keep "Expires in ..." up to date. The wording matches humanDuration in
+// cmd/web/templates.go.
+function humanDuration(ms) {
+	var units = [["day", 86400000], ["hour", 3600000], ["minute", 60000]];
+	var plural = function (n, unit) {
+		return n == 1 ? "1 " + unit : n + " " + unit + "s";
+	};
+	for (var i = 0; i < units.length; i++) {
+		if (ms < units[i][1]) {
+			continue;
+		}
+		var text = plural(Math.floor(ms / units[i][1]), units[i][0]);
+		if (i + 1 < units.length) {
+			var n = Math.floor(ms % units[i][1] / units[i + 1][1]);
+			if (n > 0) {
+				text += " " + plural(n, units[i + 1][0]);
+			}
+		}
+		return text;
+	}
+	return "less than a minute";
+}
+
+var countdowns = document.querySelectorAll("time[data-countdown]");
+if (countdowns.length > 0) {
+	var updateCountdowns = function () {
+		for (var i = 0; i < countdowns.length; i++) {
+			var left = new Date(countdowns[i].getAttribute("datetime")) - new Date();
+			countdowns[i].textContent = left > 0 ? "Expires in " + humanDuration(left) : "Expired";
+		}
+	};
+	updateCountdowns();
+	setInterval(updateCountdowns, 1000);
+}